@@ -3,7 +3,13 @@ package main
 import (
 	"context"
 	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/auth"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/campaigns"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/events"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/idempotency"
 	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/orders"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/rewards"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/vouchers"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/webhooks"
 	"log"
 
 	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/config"
@@ -21,7 +27,11 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	dbStorage := database.NewDatabaseStorage(ctx, cfg.DatabaseURI)
+	dbStorage := database.NewDatabaseStorage(ctx, cfg.DatabaseURI, database.AccountPolicy{
+		ReversalPolicy: cfg.ReversalPolicy,
+		PointsValidity: cfg.PointsValidity,
+		HoldPeriod:     cfg.HoldPeriod,
+	})
 	if dbStorage == nil {
 		log.Fatal("Не удалось инициализировать БД сервиса системы лояльности")
 	}
@@ -32,7 +42,60 @@ func main() {
 		log.Fatal(err)
 	}
 
-	orderController, err := orders.NewOrders(dbStorage, cfg.AccrualSystemAddress)
+	campaignManager, err := campaigns.NewCampaigns(dbStorage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rewardManager, err := rewards.NewRewards(dbStorage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	voucherManager, err := vouchers.NewVouchers(dbStorage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	idempotencyManager, err := idempotency.NewIdempotency(dbStorage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer idempotencyManager.Close()
+
+	eventBus, err := events.NewEvents(dbStorage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer eventBus.Close()
+
+	webhookManager, err := webhooks.NewWebhooks(dbStorage, eventBus)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer webhookManager.Close()
+
+	tiers, err := orders.ParseTiers(cfg.Tiers)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	orderController, err := orders.NewOrders(dbStorage, cfg.AccrualSystemAddress, orders.TierPolicy{
+		Tiers: tiers,
+		Basis: cfg.TierBasis,
+	}, orders.BonusPolicy{
+		WelcomeBonus:  float32(cfg.WelcomeBonus),
+		ReferralBonus: float32(cfg.ReferralBonus),
+		ReferralLimit: cfg.ReferralLimit,
+	}, orders.TransferPolicy{
+		DailyLimit:            float32(cfg.TransferDailyLimit),
+		ConfirmationThreshold: float32(cfg.TransferConfirmation),
+	}, orders.WithdrawalPolicy{
+		MinAmount:     float32(cfg.MinWithdrawal),
+		MaxOrderShare: float32(cfg.MaxOrderShare),
+		DailyLimit:    float32(cfg.WithdrawalDailyLimit),
+		MonthlyLimit:  float32(cfg.WithdrawalMonthLimit),
+	}, campaignManager, eventBus)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -40,7 +103,7 @@ func main() {
 
 	//orderController.ProcessOrder("12345678903")
 
-	handler := handlers.NewHandler(cfg.BaseURL, authenticator, orderController)
+	handler := handlers.NewHandler(cfg.BaseURL, authenticator, orderController, campaignManager, rewardManager, voucherManager, idempotencyManager, eventBus, webhookManager, cfg.AdminLogins, cfg.MerchantKeys, cfg.TrustedProxies)
 
 	srv := server.NewServer(cfg.RunAddress, handler)
 	log.Fatal(srv.ListenAndServe())
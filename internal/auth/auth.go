@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
 	"strings"
@@ -32,6 +33,7 @@ type Authenticator interface {
 	Authenticate(string) (string, error)
 	Register(string, string) (string, error)
 	Login(string, string) (string, error)
+	VerifyPassword(string, string) (bool, error)
 }
 
 func NewAuth(userController UserAdderGetter) (Authenticator, error) {
@@ -139,21 +141,22 @@ func (a *authentication) Login(login, password string) (string, error) {
 	return token, nil
 }
 
-func (a *authentication) checkPassword(login, password string) (string, error) {
+func (a *authentication) savedPasswordHash(login string) (string, error) {
 	loginHash, err := getHash(login)
 	if err != nil {
 		return "", err
 	}
 
-	savedPasswordHash := ""
 	userData, userFound := a.users[loginHash]
 	if userFound {
-		savedPasswordHash = userData.passwordHash
+		return userData.passwordHash, nil
 	}
 
-	if !userFound {
-		savedPasswordHash, err = a.userController.GetUserPassword(login)
-	}
+	return a.userController.GetUserPassword(login)
+}
+
+func (a *authentication) checkPassword(login, password string) (string, error) {
+	savedPasswordHash, err := a.savedPasswordHash(login)
 	if err != nil {
 		return "", err
 	}
@@ -188,3 +191,17 @@ func (a *authentication) Authenticate(t string) (string, error) {
 
 	return userData.login, nil
 }
+
+func (a *authentication) VerifyPassword(login, password string) (bool, error) {
+	savedPasswordHash, err := a.savedPasswordHash(login)
+	if err != nil {
+		return false, err
+	}
+
+	passwordHash, err := getHash(password)
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare([]byte(savedPasswordHash), []byte(passwordHash)) == 1, nil
+}
@@ -0,0 +1,283 @@
+package campaigns
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const timeOfDayLayout = "15:04"
+
+type Target struct {
+	User         string
+	OrderID      string
+	Tier         string
+	RegisteredAt time.Time
+	FirstOrder   bool
+	OrderTime    time.Time
+	Amount       float32
+}
+
+type Bonus struct {
+	CampaignID int64
+	Name       string
+	Amount     float32
+}
+
+type CampaignManager interface {
+	CreateCampaign(campaign *database.Campaign) error
+	UpdateCampaign(campaign *database.Campaign) error
+	DeleteCampaign(campaignID int64) error
+	GetCampaigns() ([]database.Campaign, error)
+	GetCampaign(campaignID int64) (*database.Campaign, error)
+	GetBonuses(target *Target) ([]Bonus, error)
+}
+
+type campaignController struct {
+	model database.Storager
+}
+
+func NewCampaigns(m database.Storager) (CampaignManager, error) {
+	if m == nil {
+		return nil, errors.New("не задано хранилище акций")
+	}
+
+	return &campaignController{model: m}, nil
+}
+
+func (c *campaignController) CreateCampaign(campaign *database.Campaign) error {
+	err := validateCampaign(campaign)
+	if err != nil {
+		return err
+	}
+
+	campaign.CreatedAt = database.CustomDateTime{Time: time.Now()}
+
+	err = c.model.CreateCampaign(campaign)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Создана акция '%v' (%v)\n", campaign.ID, campaign.Name)
+	return nil
+}
+
+func (c *campaignController) UpdateCampaign(campaign *database.Campaign) error {
+	err := validateCampaign(campaign)
+	if err != nil {
+		return err
+	}
+
+	err = c.model.UpdateCampaign(campaign)
+	if err != nil {
+		return c.campaignError(campaign.ID, err)
+	}
+
+	updated, err := c.model.GetCampaign(campaign.ID)
+	if err != nil {
+		return err
+	}
+
+	if updated != nil {
+		*campaign = *updated
+	}
+
+	log.Printf("Изменена акция '%v' (%v)\n", campaign.ID, campaign.Name)
+	return nil
+}
+
+func (c *campaignController) DeleteCampaign(campaignID int64) error {
+	err := c.model.DeleteCampaign(campaignID)
+	if err != nil {
+		return c.campaignError(campaignID, err)
+	}
+
+	log.Printf("Удалена акция '%v'\n", campaignID)
+	return nil
+}
+
+func (c *campaignController) GetCampaigns() ([]database.Campaign, error) {
+	return c.model.GetCampaigns()
+}
+
+func (c *campaignController) GetCampaign(campaignID int64) (*database.Campaign, error) {
+	campaign, err := c.model.GetCampaign(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	if campaign == nil {
+		return nil, NewCampaignNotFoundError(campaignID)
+	}
+
+	return campaign, nil
+}
+
+func (c *campaignController) campaignError(campaignID int64, err error) error {
+	var dbCampaignError *database.DBCampaignError
+	if errors.As(err, &dbCampaignError) && dbCampaignError.NotFound {
+		return NewCampaignNotFoundError(campaignID)
+	}
+
+	return err
+}
+
+func (c *campaignController) GetBonuses(target *Target) ([]Bonus, error) {
+	campaigns, err := c.model.GetActiveCampaigns(target.OrderTime)
+	if err != nil {
+		return nil, err
+	}
+
+	bonuses := make([]Bonus, 0)
+
+	for _, campaign := range campaigns {
+		if !isEligible(&campaign, target) {
+			continue
+		}
+
+		var amount float32
+		if campaign.Multiplier > 1 {
+			amount += target.Amount * (campaign.Multiplier - 1)
+		}
+		amount += campaign.Bonus
+
+		if amount <= 0 {
+			continue
+		}
+
+		bonuses = append(bonuses, Bonus{
+			CampaignID: campaign.ID,
+			Name:       campaign.Name,
+			Amount:     amount,
+		})
+	}
+
+	return bonuses, nil
+}
+
+func isEligible(campaign *database.Campaign, target *Target) bool {
+	if target.OrderTime.Before(campaign.StartsAt.Time) || !target.OrderTime.Before(campaign.EndsAt.Time) {
+		return false
+	}
+
+	if len(campaign.Weekdays) > 0 && !containsWeekday(campaign.Weekdays, target.OrderTime.Weekday()) {
+		return false
+	}
+
+	if !inTimeWindow(campaign.TimeFrom, campaign.TimeTo, target.OrderTime) {
+		return false
+	}
+
+	if len(campaign.Tiers) > 0 && !containsString(campaign.Tiers, target.Tier) {
+		return false
+	}
+
+	if campaign.RegisteredAfter != nil && target.RegisteredAt.Before(campaign.RegisteredAfter.Time) {
+		return false
+	}
+
+	if campaign.RegisteredBefore != nil && !target.RegisteredAt.Before(campaign.RegisteredBefore.Time) {
+		return false
+	}
+
+	if campaign.FirstOrderOnly && !target.FirstOrder {
+		return false
+	}
+
+	return true
+}
+
+func containsWeekday(weekdays []int32, weekday time.Weekday) bool {
+	for _, w := range weekdays {
+		if time.Weekday(w) == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func minutesOfDay(s string) int {
+	t, err := time.Parse(timeOfDayLayout, s)
+	if err != nil {
+		return -1
+	}
+
+	return t.Hour()*60 + t.Minute()
+}
+
+func inTimeWindow(from, to string, at time.Time) bool {
+	if from == "" && to == "" {
+		return true
+	}
+
+	current := at.Hour()*60 + at.Minute()
+	start, end := 0, 24*60
+
+	if from != "" {
+		start = minutesOfDay(from)
+	}
+
+	if to != "" {
+		end = minutesOfDay(to)
+	}
+
+	if start <= end {
+		return current >= start && current < end
+	}
+
+	return current >= start || current < end
+}
+
+func validateCampaign(campaign *database.Campaign) error {
+	if campaign.Name == "" {
+		return NewCampaignValidationError(campaign.ID, errors.New("не указано название акции"))
+	}
+
+	if campaign.StartsAt.IsZero() || campaign.EndsAt.IsZero() || !campaign.EndsAt.After(campaign.StartsAt.Time) {
+		return NewCampaignValidationError(campaign.ID, errors.New("неверно указан период действия акции"))
+	}
+
+	if campaign.Multiplier < 0 || campaign.Bonus < 0 {
+		return NewCampaignValidationError(campaign.ID, errors.New("множитель и бонус акции не могут быть отрицательными"))
+	}
+
+	if campaign.Multiplier <= 1 && campaign.Bonus == 0 {
+		return NewCampaignValidationError(campaign.ID, errors.New("для акции должен быть указан множитель больше 1 или фиксированный бонус"))
+	}
+
+	for _, weekday := range campaign.Weekdays {
+		if weekday < 0 || weekday > 6 {
+			return NewCampaignValidationError(campaign.ID, errors.New("неверно указан день недели "+strconv.Itoa(int(weekday))+", ожидается число от 0 (воскресенье) до 6"))
+		}
+	}
+
+	for _, t := range []string{campaign.TimeFrom, campaign.TimeTo} {
+		if t != "" && minutesOfDay(t) < 0 {
+			return NewCampaignValidationError(campaign.ID, errors.New("неверный формат времени '"+t+"', ожидается ЧЧ:ММ"))
+		}
+	}
+
+	if campaign.Weekdays == nil {
+		campaign.Weekdays = []int32{}
+	}
+
+	if campaign.Tiers == nil {
+		campaign.Tiers = []string{}
+	}
+
+	return nil
+}
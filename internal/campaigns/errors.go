@@ -0,0 +1,33 @@
+package campaigns
+
+import (
+	"errors"
+	"strconv"
+)
+
+type CampaignError struct {
+	CampaignID int64
+	NotFound   bool
+	Invalid    bool
+	Err        error
+}
+
+func (e CampaignError) Error() string {
+	return e.Err.Error()
+}
+
+func NewCampaignNotFoundError(campaignID int64) error {
+	return &CampaignError{
+		CampaignID: campaignID,
+		NotFound:   true,
+		Err:        errors.New("акция " + strconv.FormatInt(campaignID, 10) + " не найдена"),
+	}
+}
+
+func NewCampaignValidationError(campaignID int64, err error) error {
+	return &CampaignError{
+		CampaignID: campaignID,
+		Invalid:    true,
+		Err:        err,
+	}
+}
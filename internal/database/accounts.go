@@ -0,0 +1,515 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+)
+
+const (
+	ReversalPolicyNegative = "negative"
+	ReversalPolicyDebt     = "debt"
+
+	amountPrecision = 0.005
+)
+
+type AccountPolicy struct {
+	ReversalPolicy string
+	PointsValidity time.Duration
+	HoldPeriod     time.Duration
+}
+
+type lot struct {
+	id          int64
+	orderNumber string
+	remaining   float32
+}
+
+type lotAllocation struct {
+	lotID  int64
+	amount float32
+}
+
+func (a *Account) repayDebt(amount float32) float32 {
+	repayment := amount
+	if repayment > a.Debt {
+		repayment = a.Debt
+	}
+
+	a.Debt -= repayment
+
+	return amount - repayment
+}
+
+func (a *Account) absorbOverdraft(amount float32) float32 {
+	if a.Balance >= 0 {
+		return amount
+	}
+
+	if amount < -a.Balance {
+		return 0
+	}
+
+	return amount + a.Balance
+}
+
+func (a *Account) credit(amount float32) float32 {
+	credited := a.repayDebt(amount)
+	lotAmount := a.absorbOverdraft(credited)
+	a.Balance += credited
+
+	return lotAmount
+}
+
+func (a *Account) creditPending(amount float32) float32 {
+	credited := a.repayDebt(amount)
+	a.Pending += credited
+
+	return credited
+}
+
+func (a *Account) reverse(amount float32, policy string) {
+	if policy != ReversalPolicyDebt {
+		a.Balance -= amount
+		return
+	}
+
+	covered := amount
+	if a.Balance < covered {
+		covered = a.Balance
+	}
+
+	if covered < 0 {
+		covered = 0
+	}
+
+	a.Balance -= covered
+	a.Debt += amount - covered
+}
+
+func (s *databaseStorage) creditWithHold(account *Account, orderNumber string, amount float32, createdAt time.Time) error {
+	if s.policy.HoldPeriod <= 0 {
+		credited := account.credit(amount)
+		if credited <= 0 {
+			return nil
+		}
+
+		return s.addLot(account.UserLogin, orderNumber, credited, createdAt, nil)
+	}
+
+	credited := account.creditPending(amount)
+	if credited <= 0 {
+		return nil
+	}
+
+	availableAt := createdAt.Add(s.policy.HoldPeriod)
+	return s.addLot(account.UserLogin, orderNumber, credited, createdAt, &availableAt)
+}
+
+func (s *databaseStorage) reverseOrderAccrual(account *Account, orderNumber string, amount float32) error {
+	pendingLots, err := s.getLots(queryGetOrderPendingLots, account.UserLogin, orderNumber)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	left := amount
+
+	for _, l := range pendingLots {
+		if left < amountPrecision {
+			break
+		}
+
+		take := l.remaining
+		if take > left {
+			take = left
+		}
+
+		_, err = s.conn.Exec(ctx, queryUpdateLotRemaining, l.id, l.remaining-take)
+		if err != nil {
+			log.Println("Ошибка при отмене ожидающих зачисления баллов:", err)
+			return err
+		}
+
+		account.Pending -= take
+		left -= take
+	}
+
+	if left < amountPrecision {
+		return nil
+	}
+
+	account.reverse(left, s.policy.ReversalPolicy)
+
+	_, err = s.consumeLots(account.UserLogin, orderNumber, left)
+	return err
+}
+
+func (s *databaseStorage) addLot(user, orderNumber string, amount float32, createdAt time.Time, availableAt *time.Time) error {
+	log.Printf("Добавление партии баллов по заказу '%v' пользователя '%v' на сумму '%v'\n", orderNumber, user, amount)
+
+	var expiresAt *time.Time
+	if s.policy.PointsValidity > 0 {
+		t := createdAt.Add(s.policy.PointsValidity)
+		expiresAt = &t
+	}
+
+	ctx := context.Background()
+
+	_, err := s.conn.Exec(ctx, queryInsertLot, user, orderNumber, amount, createdAt, expiresAt, availableAt, availableAt == nil)
+	if err != nil {
+		log.Println("Ошибка при добавлении партии баллов в БД:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) getLots(query string, args ...any) ([]lot, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		log.Println("Ошибка при запросе партий баллов:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]lot, 0)
+
+	for rows.Next() {
+		var l lot
+		err = rows.Scan(&l.id, &l.orderNumber, &l.remaining)
+		if err != nil {
+			log.Println("Ошибка при считывании партии баллов из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, l)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании партий баллов из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) consumeLots(user, orderNumber string, amount float32) ([]lotAllocation, error) {
+	lots := make([]lot, 0)
+
+	if orderNumber != "" {
+		orderLots, err := s.getLots(queryGetOrderLots, user, orderNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		lots = append(lots, orderLots...)
+	}
+
+	activeLots, err := s.getLots(queryGetActiveLots, user, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	lots = append(lots, activeLots...)
+
+	remaining := make(map[int64]float32, len(lots))
+	for _, l := range lots {
+		remaining[l.id] = l.remaining
+	}
+
+	ctx := context.Background()
+	allocations := make([]lotAllocation, 0)
+
+	for _, allocation := range allocateLots(lots, amount) {
+		_, err = s.conn.Exec(ctx, queryUpdateLotRemaining, allocation.lotID, remaining[allocation.lotID]-allocation.amount)
+		if err != nil {
+			log.Println("Ошибка при списании баллов из партии:", err)
+			return allocations, err
+		}
+
+		allocations = append(allocations, allocation)
+	}
+
+	return allocations, nil
+}
+
+func allocateLots(lots []lot, amount float32) []lotAllocation {
+	allocations := make([]lotAllocation, 0)
+	consumed := make(map[int64]struct{})
+	left := amount
+
+	for _, l := range lots {
+		if left < amountPrecision {
+			break
+		}
+
+		if _, ok := consumed[l.id]; ok {
+			continue
+		}
+		consumed[l.id] = struct{}{}
+
+		take := l.remaining
+		if take > left {
+			take = left
+		}
+
+		allocations = append(allocations, lotAllocation{lotID: l.id, amount: take})
+		left -= take
+	}
+
+	return allocations
+}
+
+func (s *databaseStorage) Withdraw(transaction *Transaction, limits WithdrawalLimits) error {
+	log.Printf("Списание баллов в счёт заказа '%v', пользователь '%v', сумма '%v'\n", transaction.OrderNumber, transaction.UserLogin, transaction.Amount)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		err := tx.refreshUserLots(transaction.UserLogin)
+		if err != nil {
+			return err
+		}
+
+		account, err := tx.GetUserAccount(transaction.UserLogin)
+		if err != nil {
+			return err
+		}
+
+		if transaction.Amount > account.Balance {
+			return NewDBAccountError(transaction.UserLogin, true, errors.New("на счёте пользователя "+transaction.UserLogin+" недостаточно средств ("+strconv.FormatFloat(float64(account.Balance), 'E', -1, 32)+") для списания "+strconv.FormatFloat(float64(transaction.Amount), 'E', -1, 32)+" баллов"))
+		}
+
+		err = tx.checkWithdrawalLimits(transaction.UserLogin, transaction.Amount, limits)
+		if err != nil {
+			return err
+		}
+
+		err = tx.AddTransaction(transaction)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.consumeLots(transaction.UserLogin, "", transaction.Amount)
+		if err != nil {
+			return err
+		}
+
+		account.Balance -= transaction.Amount
+		account.Withdrawn += transaction.Amount
+
+		return tx.UpdateUserAccount(account)
+	})
+}
+
+func (s *databaseStorage) refreshUserLots(user string) error {
+	err := s.releaseUserLots(user)
+	if err != nil {
+		return err
+	}
+
+	return s.expireUserLots(user)
+}
+
+func (s *databaseStorage) expireUserLots(user string) error {
+	return s.inTransaction(func(tx *databaseStorage) error {
+		return tx.expireLots(user)
+	})
+}
+
+func (s *databaseStorage) expireLots(user string) error {
+	now := time.Now()
+
+	lots, err := s.getLots(queryGetExpiredLots, user, now)
+	if err != nil {
+		return err
+	}
+
+	if len(lots) == 0 {
+		return nil
+	}
+
+	account, err := s.GetUserAccount(user)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	for _, l := range lots {
+		transaction := Transaction{
+			OrderNumber: l.orderNumber,
+			UserLogin:   user,
+			Type:        TransactionTypeExpiration,
+			Amount:      l.remaining,
+			CreatedAt:   CustomDateTime{Time: now},
+		}
+
+		err = s.AddTransaction(&transaction)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.conn.Exec(ctx, queryUpdateLotRemaining, l.id, 0)
+		if err != nil {
+			log.Println("Ошибка при сгорании партии баллов:", err)
+			return err
+		}
+
+		account.Balance -= l.remaining
+		log.Printf("Сгорели баллы по заказу '%v' пользователя '%v' на сумму '%v'\n", l.orderNumber, user, l.remaining)
+	}
+
+	return s.UpdateUserAccount(account)
+}
+
+func (s *databaseStorage) getUsers(query string, args ...any) ([]string, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		log.Println("Ошибка при запросе списка пользователей:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := make([]string, 0)
+
+	for rows.Next() {
+		var user string
+		err = rows.Scan(&user)
+		if err != nil {
+			log.Println("Ошибка при считывании пользователя из списка:", err)
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании списка пользователей:", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (s *databaseStorage) ExpirePoints() error {
+	users, err := s.getUsers(queryGetUsersWithExpiredLots, time.Now())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Найдено %v пользователей со сгорающими баллами\n", len(users))
+
+	for _, user := range users {
+		s.locker.account.Lock()
+		err = s.expireUserLots(user)
+		s.locker.account.Unlock()
+
+		if err != nil {
+			log.Println("Ошибка при сгорании баллов пользователя "+user+":", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) releaseUserLots(user string) error {
+	return s.inTransaction(func(tx *databaseStorage) error {
+		return tx.releaseLots(user)
+	})
+}
+
+func (s *databaseStorage) releaseLots(user string) error {
+	lots, err := s.getLots(queryGetReleasableLots, user, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(lots) == 0 {
+		return nil
+	}
+
+	account, err := s.GetUserAccount(user)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	for _, l := range lots {
+		_, err = s.conn.Exec(ctx, queryReleaseLot, l.id)
+		if err != nil {
+			log.Println("Ошибка при зачислении ожидающих баллов:", err)
+			return err
+		}
+
+		lotAmount := account.absorbOverdraft(l.remaining)
+		if lotAmount < l.remaining {
+			_, err = s.conn.Exec(ctx, queryUpdateLotRemaining, l.id, lotAmount)
+			if err != nil {
+				log.Println("Ошибка при погашении отрицательного баланса из ожидающих баллов:", err)
+				return err
+			}
+		}
+
+		account.Pending -= l.remaining
+		account.Balance += l.remaining
+		log.Printf("Зачислены ожидающие баллы по заказу '%v' пользователя '%v' на сумму '%v'\n", l.orderNumber, user, l.remaining)
+	}
+
+	if account.Pending < amountPrecision {
+		account.Pending = 0
+	}
+
+	return s.UpdateUserAccount(account)
+}
+
+func (s *databaseStorage) ReleasePendingPoints() error {
+	users, err := s.getUsers(queryGetUsersWithReleasableLots, time.Now())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Найдено %v пользователей с баллами, ожидающими зачисления\n", len(users))
+
+	for _, user := range users {
+		s.locker.account.Lock()
+		err = s.releaseUserLots(user)
+		s.locker.account.Unlock()
+
+		if err != nil {
+			log.Println("Ошибка при зачислении ожидающих баллов пользователя "+user+":", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) GetExpiringPoints(user string, period time.Duration) (float32, error) {
+	ctx := context.Background()
+	now := time.Now()
+	var amount float32
+
+	row := s.conn.QueryRow(ctx, queryGetExpiringPoints, user, now, now.Add(period))
+	err := row.Scan(&amount)
+	if err != nil {
+		log.Println("Ошибка при считывании сгорающих баллов пользователя "+user+":", err)
+		return 0, err
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccountRepayDebt(t *testing.T) {
+	tests := []struct {
+		name     string
+		debt     float32
+		amount   float32
+		wantLeft float32
+		wantDebt float32
+	}{
+		{name: "без долга", debt: 0, amount: 4, wantLeft: 4, wantDebt: 0},
+		{name: "долг больше суммы", debt: 5, amount: 3, wantLeft: 0, wantDebt: 2},
+		{name: "долг меньше суммы", debt: 2, amount: 5, wantLeft: 3, wantDebt: 0},
+		{name: "долг равен сумме", debt: 5, amount: 5, wantLeft: 0, wantDebt: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := Account{Debt: tt.debt}
+
+			left := account.repayDebt(tt.amount)
+			if left != tt.wantLeft {
+				t.Errorf("repayDebt() = %v, ожидалось %v", left, tt.wantLeft)
+			}
+
+			if account.Debt != tt.wantDebt {
+				t.Errorf("Debt = %v, ожидалось %v", account.Debt, tt.wantDebt)
+			}
+		})
+	}
+}
+
+func TestAccountCredit(t *testing.T) {
+	tests := []struct {
+		name        string
+		account     Account
+		amount      float32
+		wantLot     float32
+		wantBalance float32
+		wantDebt    float32
+	}{
+		{name: "обычное начисление", account: Account{Balance: 10}, amount: 5, wantLot: 5, wantBalance: 15},
+		{name: "частичное погашение долга", account: Account{Debt: 3}, amount: 5, wantLot: 2, wantBalance: 2},
+		{name: "полное погашение начисления долгом", account: Account{Debt: 10}, amount: 5, wantLot: 0, wantBalance: 0, wantDebt: 5},
+		{name: "покрытие отрицательного баланса", account: Account{Balance: -4}, amount: 10, wantLot: 6, wantBalance: 6},
+		{name: "баланс остаётся отрицательным", account: Account{Balance: -4}, amount: 3, wantLot: 0, wantBalance: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := tt.account
+
+			lot := account.credit(tt.amount)
+			if lot != tt.wantLot {
+				t.Errorf("credit() = %v, ожидалось %v", lot, tt.wantLot)
+			}
+
+			if account.Balance != tt.wantBalance {
+				t.Errorf("Balance = %v, ожидалось %v", account.Balance, tt.wantBalance)
+			}
+
+			if account.Debt != tt.wantDebt {
+				t.Errorf("Debt = %v, ожидалось %v", account.Debt, tt.wantDebt)
+			}
+		})
+	}
+}
+
+func TestAccountReverse(t *testing.T) {
+	tests := []struct {
+		name        string
+		account     Account
+		amount      float32
+		policy      string
+		wantBalance float32
+		wantDebt    float32
+	}{
+		{name: "отрицательный баланс", account: Account{Balance: 3}, amount: 5, policy: ReversalPolicyNegative, wantBalance: -2},
+		{name: "неизвестная политика", account: Account{Balance: 3}, amount: 5, policy: "", wantBalance: -2},
+		{name: "долг при нехватке средств", account: Account{Balance: 3}, amount: 5, policy: ReversalPolicyDebt, wantBalance: 0, wantDebt: 2},
+		{name: "без долга при достаточном балансе", account: Account{Balance: 10}, amount: 4, policy: ReversalPolicyDebt, wantBalance: 6},
+		{name: "долг при отрицательном балансе", account: Account{Balance: -2}, amount: 4, policy: ReversalPolicyDebt, wantBalance: -2, wantDebt: 4},
+		{name: "долг добавляется к существующему", account: Account{Debt: 1}, amount: 4, policy: ReversalPolicyDebt, wantDebt: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := tt.account
+			account.reverse(tt.amount, tt.policy)
+
+			if account.Balance != tt.wantBalance {
+				t.Errorf("Balance = %v, ожидалось %v", account.Balance, tt.wantBalance)
+			}
+
+			if account.Debt != tt.wantDebt {
+				t.Errorf("Debt = %v, ожидалось %v", account.Debt, tt.wantDebt)
+			}
+		})
+	}
+}
+
+func TestAllocateLots(t *testing.T) {
+	tests := []struct {
+		name   string
+		lots   []lot
+		amount float32
+		want   []lotAllocation
+	}{
+		{
+			name:   "списание из нескольких партий",
+			lots:   []lot{{id: 1, remaining: 5}, {id: 2, remaining: 5}},
+			amount: 7,
+			want:   []lotAllocation{{lotID: 1, amount: 5}, {lotID: 2, amount: 2}},
+		},
+		{
+			name:   "повторная партия пропускается",
+			lots:   []lot{{id: 1, orderNumber: "12345678903", remaining: 3}, {id: 1, remaining: 3}, {id: 2, remaining: 4}},
+			amount: 5,
+			want:   []lotAllocation{{lotID: 1, amount: 3}, {lotID: 2, amount: 2}},
+		},
+		{
+			name:   "сумма больше остатка партий",
+			lots:   []lot{{id: 1, remaining: 5}, {id: 2, remaining: 5}},
+			amount: 20,
+			want:   []lotAllocation{{lotID: 1, amount: 5}, {lotID: 2, amount: 5}},
+		},
+		{
+			name:   "нулевая сумма",
+			lots:   []lot{{id: 1, remaining: 5}},
+			amount: 0,
+			want:   []lotAllocation{},
+		},
+		{
+			name:   "нет партий",
+			lots:   nil,
+			amount: 5,
+			want:   []lotAllocation{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocateLots(tt.lots, tt.amount)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allocateLots() = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
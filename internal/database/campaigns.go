@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Campaign struct {
+	ID               int64           `json:"id"`
+	Name             string          `json:"name"`
+	StartsAt         CustomDateTime  `json:"starts_at"`
+	EndsAt           CustomDateTime  `json:"ends_at"`
+	Multiplier       float32         `json:"multiplier,omitempty"`
+	Bonus            float32         `json:"bonus,omitempty"`
+	Weekdays         []int32         `json:"weekdays,omitempty"`
+	TimeFrom         string          `json:"time_from,omitempty"`
+	TimeTo           string          `json:"time_to,omitempty"`
+	Tiers            []string        `json:"tiers,omitempty"`
+	RegisteredAfter  *CustomDateTime `json:"registered_after,omitempty"`
+	RegisteredBefore *CustomDateTime `json:"registered_before,omitempty"`
+	FirstOrderOnly   bool            `json:"first_order_only"`
+	Active           bool            `json:"active"`
+	CreatedAt        CustomDateTime  `json:"created_at"`
+}
+
+func optionalTime(t *CustomDateTime) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func (s *databaseStorage) CreateCampaign(campaign *Campaign) error {
+	log.Printf("Добавление в БД акции '%v'\n", campaign.Name)
+
+	ctx := context.Background()
+
+	err := s.conn.QueryRow(ctx, queryInsertCampaign, campaign.Name, campaign.StartsAt.Time, campaign.EndsAt.Time,
+		campaign.Multiplier, campaign.Bonus, campaign.Weekdays, campaign.TimeFrom, campaign.TimeTo, campaign.Tiers,
+		optionalTime(campaign.RegisteredAfter), optionalTime(campaign.RegisteredBefore), campaign.FirstOrderOnly,
+		campaign.Active, campaign.CreatedAt.Time).Scan(&campaign.ID)
+	if err != nil {
+		log.Println("Ошибка при добавлении акции в БД:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) UpdateCampaign(campaign *Campaign) error {
+	log.Printf("Обновление в БД акции '%v'\n", campaign.ID)
+
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryUpdateCampaign, campaign.ID, campaign.Name, campaign.StartsAt.Time, campaign.EndsAt.Time,
+		campaign.Multiplier, campaign.Bonus, campaign.Weekdays, campaign.TimeFrom, campaign.TimeTo, campaign.Tiers,
+		optionalTime(campaign.RegisteredAfter), optionalTime(campaign.RegisteredBefore), campaign.FirstOrderOnly,
+		campaign.Active)
+	if err != nil {
+		log.Println("Ошибка при обновлении акции в БД:", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return NewDBCampaignError(campaign.ID, true, errors.New("акция "+strconv.FormatInt(campaign.ID, 10)+" не найдена"))
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) DeleteCampaign(campaignID int64) error {
+	log.Printf("Удаление акции '%v'\n", campaignID)
+
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryDeleteCampaign, campaignID, time.Now())
+	if err != nil {
+		log.Println("Ошибка при удалении акции из БД:", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return NewDBCampaignError(campaignID, true, errors.New("акция "+strconv.FormatInt(campaignID, 10)+" не найдена"))
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) GetCampaigns() ([]Campaign, error) {
+	return s.getCampaigns(queryGetCampaigns)
+}
+
+func (s *databaseStorage) GetActiveCampaigns(at time.Time) ([]Campaign, error) {
+	return s.getCampaigns(queryGetActiveCampaigns, at)
+}
+
+func (s *databaseStorage) GetCampaign(campaignID int64) (*Campaign, error) {
+	ctx := context.Background()
+
+	campaign, err := scanCampaign(s.conn.QueryRow(ctx, queryGetCampaign, campaignID))
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании акции из БД:", err)
+		return nil, err
+	}
+
+	return campaign, nil
+}
+
+func (s *databaseStorage) getCampaigns(query string, args ...any) ([]Campaign, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		log.Println("Ошибка при запросе акций:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]Campaign, 0)
+
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			log.Println("Ошибка при считывании акции из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, *campaign)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании акций из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanCampaign(row pgx.Row) (*Campaign, error) {
+	var campaign Campaign
+	var registeredAfter, registeredBefore *time.Time
+
+	err := row.Scan(&campaign.ID, &campaign.Name, &campaign.StartsAt.Time, &campaign.EndsAt.Time, &campaign.Multiplier,
+		&campaign.Bonus, &campaign.Weekdays, &campaign.TimeFrom, &campaign.TimeTo, &campaign.Tiers,
+		&registeredAfter, &registeredBefore, &campaign.FirstOrderOnly, &campaign.Active, &campaign.CreatedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	if registeredAfter != nil {
+		campaign.RegisteredAfter = &CustomDateTime{Time: *registeredAfter}
+	}
+
+	if registeredBefore != nil {
+		campaign.RegisteredBefore = &CustomDateTime{Time: *registeredBefore}
+	}
+
+	return &campaign, nil
+}
+
+func (s *databaseStorage) GetUserRegisteredAt(user string) (time.Time, error) {
+	ctx := context.Background()
+	var registeredAt time.Time
+
+	err := s.conn.QueryRow(ctx, queryGetUserRegisteredAt, user).Scan(&registeredAt)
+	if err != nil {
+		log.Println("Ошибка при считывании даты регистрации пользователя "+user+":", err)
+		return time.Time{}, err
+	}
+
+	return registeredAt, nil
+}
+
+func (s *databaseStorage) IsFirstProcessedOrder(user, orderNumber string) (bool, error) {
+	ctx := context.Background()
+	var count int
+
+	err := s.conn.QueryRow(ctx, queryCountProcessedOrders, user, orderNumber).Scan(&count)
+	if err != nil {
+		log.Println("Ошибка при подсчёте обработанных заказов пользователя "+user+":", err)
+		return false, err
+	}
+
+	return count == 0, nil
+}
+
+func (s *databaseStorage) AddCampaignBonus(user, orderNumber string, campaignID int64, amount float32) error {
+	log.Printf("Начисление бонуса по акции '%v' за заказ '%v' пользователя '%v' на сумму '%v'\n", campaignID, orderNumber, user, amount)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		ctx := context.Background()
+
+		var exists bool
+		err := tx.conn.QueryRow(ctx, queryCheckCampaignBonusExists, orderNumber, campaignID).Scan(&exists)
+		if err != nil {
+			log.Println("Ошибка при проверке бонусов по акции для заказа "+orderNumber+":", err)
+			return err
+		}
+
+		if exists {
+			log.Printf("Бонус по акции '%v' за заказ '%v' уже начислен\n", campaignID, orderNumber)
+			return nil
+		}
+
+		return tx.creditBonus(&Transaction{
+			OrderNumber: orderNumber,
+			UserLogin:   user,
+			Type:        TransactionTypeCampaign,
+			Amount:      amount,
+			CreatedAt:   CustomDateTime{Time: time.Now()},
+			CampaignID:  campaignID,
+		}, true)
+	})
+}
@@ -23,6 +23,20 @@ const sqlCreateTableUsers = `
 	TABLESPACE pg_default;	
 `
 
+const sqlMigrateTableUsers = `
+	ALTER TABLE public.users ADD COLUMN IF NOT EXISTS referral_code character varying(16) COLLATE pg_catalog."default";
+	ALTER TABLE public.users ADD COLUMN IF NOT EXISTS referred_by character varying COLLATE pg_catalog."default";
+	ALTER TABLE public.users ADD COLUMN IF NOT EXISTS referral_settled boolean NOT NULL DEFAULT false;
+	ALTER TABLE public.users ADD COLUMN IF NOT EXISTS registration_ip character varying(64) COLLATE pg_catalog."default";
+	ALTER TABLE public.users ADD COLUMN IF NOT EXISTS registered_at timestamp with time zone NOT NULL DEFAULT now();
+
+	CREATE UNIQUE INDEX IF NOT EXISTS users_referral_code_idx
+	ON public.users (referral_code);
+
+	CREATE INDEX IF NOT EXISTS users_referred_by_idx
+	ON public.users (referred_by);
+`
+
 const sqlCreateTableOrders = `
 	CREATE TABLE IF NOT EXISTS public.orders
 	(
@@ -36,28 +50,448 @@ const sqlCreateTableOrders = `
 	TABLESPACE pg_default;
 `
 
+const sqlMigrateTableOrders = `
+	CREATE INDEX IF NOT EXISTS orders_user_login_uploaded_idx
+	ON public.orders (user_login, uploaded, id);
+
+	ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS accrual_multiplier real;
+`
+
 const sqlCreateTableAccounts = `
 	CREATE TABLE IF NOT EXISTS public.accounts
 	(
 		user_login character varying COLLATE pg_catalog."default" NOT NULL,
 		balance real NOT NULL DEFAULT 0,
 		withdrawn real NOT NULL DEFAULT 0,
+		debt real NOT NULL DEFAULT 0,
+		pending real NOT NULL DEFAULT 0,
+		held real NOT NULL DEFAULT 0,
 		CONSTRAINT accounts_pkey PRIMARY KEY (user_login)
 	)
 	
 	TABLESPACE pg_default;
 `
 
+const sqlMigrateTableAccounts = `
+	ALTER TABLE public.accounts ADD COLUMN IF NOT EXISTS debt real NOT NULL DEFAULT 0;
+	ALTER TABLE public.accounts ADD COLUMN IF NOT EXISTS pending real NOT NULL DEFAULT 0;
+	ALTER TABLE public.accounts ADD COLUMN IF NOT EXISTS held real NOT NULL DEFAULT 0;
+`
+
 const sqlCreateTableTransactions = `
 	CREATE TABLE IF NOT EXISTS public.transactions
 	(
+		id bigserial NOT NULL,
 		order_number character varying COLLATE pg_catalog."default" NOT NULL,
 		user_login character varying COLLATE pg_catalog."default" NOT NULL,
-		type character varying(10) COLLATE pg_catalog."default" NOT NULL,
+		type character varying(20) COLLATE pg_catalog."default" NOT NULL,
 		amount real NOT NULL DEFAULT 0,
 		created_at timestamp with time zone NOT NULL,
-		CONSTRAINT transactions_pkey PRIMARY KEY (order_number)
+		CONSTRAINT transactions_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+`
+
+const sqlMigrateTableTransactions = `
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = 'public' AND table_name = 'transactions' AND column_name = 'id'
+		) THEN
+			ALTER TABLE public.transactions DROP CONSTRAINT transactions_pkey;
+			ALTER TABLE public.transactions ADD COLUMN id bigserial NOT NULL;
+			ALTER TABLE public.transactions ADD CONSTRAINT transactions_pkey PRIMARY KEY (id);
+			ALTER TABLE public.transactions ALTER COLUMN type TYPE character varying(20);
+		END IF;
+	END $$;
+
+	CREATE UNIQUE INDEX IF NOT EXISTS transactions_withdrawal_order_idx
+	ON public.transactions (order_number)
+	WHERE type = 'WITHDRAWAL';
+
+	CREATE INDEX IF NOT EXISTS transactions_order_number_idx
+	ON public.transactions (order_number);
+
+	CREATE INDEX IF NOT EXISTS transactions_user_login_idx
+	ON public.transactions (user_login, created_at);
+
+	ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS campaign_id bigint;
+	ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS merchant_id character varying COLLATE pg_catalog."default";
+
+	CREATE UNIQUE INDEX IF NOT EXISTS transactions_campaign_order_idx
+	ON public.transactions (order_number, campaign_id)
+	WHERE type = 'CAMPAIGN';
+`
+
+const sqlCreateTableOrderHistory = `
+	CREATE TABLE IF NOT EXISTS public.order_history
+	(
+		id bigserial NOT NULL,
+		order_id character varying(20) COLLATE pg_catalog."default" NOT NULL,
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		old_status character varying(10) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		new_status character varying(10) COLLATE pg_catalog."default" NOT NULL,
+		accrual real NOT NULL DEFAULT 0,
+		source character varying(10) COLLATE pg_catalog."default" NOT NULL,
+		created_at timestamp with time zone NOT NULL,
+		CONSTRAINT order_history_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS order_history_order_id_idx
+	ON public.order_history (order_id, created_at);
+`
+
+const sqlCreateTableAccrualLots = `
+	CREATE TABLE IF NOT EXISTS public.accrual_lots
+	(
+		id bigserial NOT NULL,
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		order_number character varying COLLATE pg_catalog."default" NOT NULL,
+		amount real NOT NULL DEFAULT 0,
+		remaining real NOT NULL DEFAULT 0,
+		created_at timestamp with time zone NOT NULL,
+		expires_at timestamp with time zone,
+		available_at timestamp with time zone,
+		released boolean NOT NULL DEFAULT true,
+		CONSTRAINT accrual_lots_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	ALTER TABLE public.accrual_lots ADD COLUMN IF NOT EXISTS available_at timestamp with time zone;
+	ALTER TABLE public.accrual_lots ADD COLUMN IF NOT EXISTS released boolean NOT NULL DEFAULT true;
+
+	CREATE INDEX IF NOT EXISTS accrual_lots_user_login_idx
+	ON public.accrual_lots (user_login, created_at)
+	WHERE remaining > 0;
+
+	CREATE INDEX IF NOT EXISTS accrual_lots_expires_at_idx
+	ON public.accrual_lots (expires_at)
+	WHERE remaining > 0;
+
+	CREATE INDEX IF NOT EXISTS accrual_lots_available_at_idx
+	ON public.accrual_lots (available_at)
+	WHERE NOT released;
+`
+
+const sqlMigrateAccrualLots = `
+	CREATE TABLE IF NOT EXISTS public.schema_migrations
+	(
+		name character varying COLLATE pg_catalog."default" NOT NULL,
+		applied_at timestamp with time zone NOT NULL,
+		CONSTRAINT schema_migrations_pkey PRIMARY KEY (name)
+	)
+
+	TABLESPACE pg_default;
+
+	WITH applied AS (
+		INSERT INTO public.schema_migrations (name, applied_at)
+		VALUES ('accrual_lots_opening_balances', now())
+		ON CONFLICT DO NOTHING
+		RETURNING name
+	), lots AS (
+		SELECT user_login, SUM(remaining) AS remaining
+		FROM public.accrual_lots
+		WHERE released
+		GROUP BY user_login
+	)
+	INSERT INTO public.accrual_lots
+		(
+			user_login, order_number, amount, remaining, created_at, released
+		)
+	SELECT a.user_login, 'OPENING-BALANCE', a.balance - COALESCE(l.remaining, 0), a.balance - COALESCE(l.remaining, 0), now(), true
+	FROM public.accounts a
+		LEFT JOIN lots l ON l.user_login = a.user_login
+	WHERE EXISTS (SELECT 1 FROM applied) AND a.balance - COALESCE(l.remaining, 0) > 0.005;
+`
+
+const sqlCreateTableBalanceHolds = `
+	CREATE TABLE IF NOT EXISTS public.balance_holds
+	(
+		id bigserial NOT NULL,
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		order_number character varying COLLATE pg_catalog."default" NOT NULL,
+		amount real NOT NULL DEFAULT 0,
+		status character varying(10) COLLATE pg_catalog."default" NOT NULL,
+		created_at timestamp with time zone NOT NULL,
+		expires_at timestamp with time zone NOT NULL,
+		finished_at timestamp with time zone,
+		CONSTRAINT balance_holds_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE UNIQUE INDEX IF NOT EXISTS balance_holds_active_order_idx
+	ON public.balance_holds (order_number)
+	WHERE status = 'ACTIVE';
+
+	CREATE INDEX IF NOT EXISTS balance_holds_expires_at_idx
+	ON public.balance_holds (expires_at)
+	WHERE status = 'ACTIVE';
+
+	CREATE TABLE IF NOT EXISTS public.balance_hold_lots
+	(
+		hold_id bigint NOT NULL,
+		lot_id bigint NOT NULL,
+		amount real NOT NULL DEFAULT 0,
+		CONSTRAINT balance_hold_lots_pkey PRIMARY KEY (hold_id, lot_id)
+	)
+	
+	TABLESPACE pg_default;
+`
+
+const sqlCreateTableUserTiers = `
+	CREATE TABLE IF NOT EXISTS public.user_tiers
+	(
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		tier character varying(20) COLLATE pg_catalog."default" NOT NULL,
+		accrued real NOT NULL DEFAULT 0,
+		evaluated_at timestamp with time zone NOT NULL,
+		CONSTRAINT user_tiers_pkey PRIMARY KEY (user_login)
+	)
+	
+	TABLESPACE pg_default;
+`
+
+const sqlCreateTableTransfers = `
+	CREATE TABLE IF NOT EXISTS public.transfers
+	(
+		id bigserial NOT NULL,
+		sender_login character varying COLLATE pg_catalog."default" NOT NULL,
+		recipient_login character varying COLLATE pg_catalog."default" NOT NULL,
+		amount real NOT NULL DEFAULT 0,
+		note character varying(200) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		status character varying(10) COLLATE pg_catalog."default" NOT NULL,
+		created_at timestamp with time zone NOT NULL,
+		expires_at timestamp with time zone,
+		completed_at timestamp with time zone,
+		CONSTRAINT transfers_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	ALTER TABLE public.transfers ADD COLUMN IF NOT EXISTS failed_attempts integer NOT NULL DEFAULT 0;
+	ALTER TABLE public.transfers DROP COLUMN IF EXISTS confirmation_code;
+
+	CREATE INDEX IF NOT EXISTS transfers_sender_login_idx
+	ON public.transfers (sender_login, created_at);
+
+	CREATE INDEX IF NOT EXISTS transfers_recipient_login_idx
+	ON public.transfers (recipient_login, created_at);
+`
+
+const sqlCreateTableCampaigns = `
+	CREATE TABLE IF NOT EXISTS public.campaigns
+	(
+		id bigserial NOT NULL,
+		name character varying(100) COLLATE pg_catalog."default" NOT NULL,
+		starts_at timestamp with time zone NOT NULL,
+		ends_at timestamp with time zone NOT NULL,
+		multiplier real NOT NULL DEFAULT 0,
+		bonus real NOT NULL DEFAULT 0,
+		weekdays integer[] NOT NULL DEFAULT '{}',
+		time_from character varying(5) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		time_to character varying(5) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		tiers character varying[] NOT NULL DEFAULT '{}',
+		registered_after timestamp with time zone,
+		registered_before timestamp with time zone,
+		first_order_only boolean NOT NULL DEFAULT false,
+		active boolean NOT NULL DEFAULT true,
+		created_at timestamp with time zone NOT NULL,
+		deleted_at timestamp with time zone,
+		CONSTRAINT campaigns_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS campaigns_period_idx
+	ON public.campaigns (starts_at, ends_at)
+	WHERE active AND deleted_at IS NULL;
+`
+
+const sqlCreateTableRewards = `
+	CREATE TABLE IF NOT EXISTS public.reward_items
+	(
+		id bigserial NOT NULL,
+		name character varying(100) COLLATE pg_catalog."default" NOT NULL,
+		description character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		price real NOT NULL,
+		stock integer,
+		valid_from timestamp with time zone,
+		valid_until timestamp with time zone,
+		active boolean NOT NULL DEFAULT true,
+		created_at timestamp with time zone NOT NULL,
+		deleted_at timestamp with time zone,
+		CONSTRAINT reward_items_pkey PRIMARY KEY (id)
 	)
 	
 	TABLESPACE pg_default;
+
+	CREATE TABLE IF NOT EXISTS public.redemptions
+	(
+		id bigserial NOT NULL,
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		item_id bigint NOT NULL,
+		quantity integer NOT NULL DEFAULT 1,
+		amount real NOT NULL,
+		status character varying(10) COLLATE pg_catalog."default" NOT NULL,
+		created_at timestamp with time zone NOT NULL,
+		updated_at timestamp with time zone NOT NULL,
+		CONSTRAINT redemptions_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS redemptions_user_login_idx
+	ON public.redemptions (user_login, created_at);
+`
+
+const sqlCreateTableVouchers = `
+	CREATE TABLE IF NOT EXISTS public.voucher_batches
+	(
+		id bigserial NOT NULL,
+		name character varying(100) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		value real NOT NULL,
+		quantity integer NOT NULL,
+		expires_at timestamp with time zone,
+		created_by character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		created_at timestamp with time zone NOT NULL,
+		CONSTRAINT voucher_batches_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE TABLE IF NOT EXISTS public.vouchers
+	(
+		code character varying(16) COLLATE pg_catalog."default" NOT NULL,
+		batch_id bigint NOT NULL,
+		value real NOT NULL,
+		expires_at timestamp with time zone,
+		redeemed_by character varying COLLATE pg_catalog."default",
+		redeemed_at timestamp with time zone,
+		CONSTRAINT vouchers_pkey PRIMARY KEY (code)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS vouchers_batch_id_idx
+	ON public.vouchers (batch_id);
+
+	CREATE TABLE IF NOT EXISTS public.voucher_attempts
+	(
+		id bigserial NOT NULL,
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		ip character varying(64) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		success boolean NOT NULL,
+		created_at timestamp with time zone NOT NULL,
+		CONSTRAINT voucher_attempts_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS voucher_attempts_user_login_idx
+	ON public.voucher_attempts (user_login, created_at)
+	WHERE NOT success;
+
+	CREATE INDEX IF NOT EXISTS voucher_attempts_ip_idx
+	ON public.voucher_attempts (ip, created_at)
+	WHERE NOT success;
+`
+
+const sqlCreateTableIdempotencyKeys = `
+	CREATE TABLE IF NOT EXISTS public.idempotency_keys
+	(
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		key character varying(255) COLLATE pg_catalog."default" NOT NULL,
+		request_hash character varying(64) COLLATE pg_catalog."default" NOT NULL,
+		status_code integer NOT NULL DEFAULT 0,
+		content_type character varying(255) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		body bytea,
+		created_at timestamp with time zone NOT NULL,
+		CONSTRAINT idempotency_keys_pkey PRIMARY KEY (user_login, key)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx
+	ON public.idempotency_keys (created_at);
+`
+
+const sqlMigrateTableIdempotencyKeys = `
+	ALTER TABLE public.idempotency_keys ADD COLUMN IF NOT EXISTS locked_until timestamp with time zone;
+	ALTER TABLE public.idempotency_keys ADD COLUMN IF NOT EXISTS owner_token character varying(64) COLLATE pg_catalog."default" NOT NULL DEFAULT '';
+`
+
+const sqlCreateTableEvents = `
+	CREATE TABLE IF NOT EXISTS public.events
+	(
+		id bigserial NOT NULL,
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		type character varying(50) COLLATE pg_catalog."default" NOT NULL,
+		payload text COLLATE pg_catalog."default" NOT NULL,
+		created_at timestamp with time zone NOT NULL,
+		CONSTRAINT events_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS events_user_login_id_idx
+	ON public.events (user_login, id);
+
+	CREATE INDEX IF NOT EXISTS events_created_at_idx
+	ON public.events (created_at);
+`
+
+const sqlCreateTableWebhooks = `
+	CREATE TABLE IF NOT EXISTS public.webhooks
+	(
+		id bigserial NOT NULL,
+		user_login character varying COLLATE pg_catalog."default" NOT NULL,
+		url character varying(2048) COLLATE pg_catalog."default" NOT NULL,
+		secret character varying(128) COLLATE pg_catalog."default" NOT NULL,
+		event_types text[] NOT NULL,
+		active boolean NOT NULL DEFAULT true,
+		failure_count integer NOT NULL DEFAULT 0,
+		disabled_at timestamp with time zone,
+		created_at timestamp with time zone NOT NULL,
+		updated_at timestamp with time zone NOT NULL,
+		CONSTRAINT webhooks_pkey PRIMARY KEY (id)
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS webhooks_user_login_idx
+	ON public.webhooks (user_login);
+
+	CREATE TABLE IF NOT EXISTS public.webhook_deliveries
+	(
+		id bigserial NOT NULL,
+		webhook_id bigint NOT NULL,
+		event_id bigint NOT NULL,
+		event_type character varying(50) COLLATE pg_catalog."default" NOT NULL,
+		payload text COLLATE pg_catalog."default" NOT NULL,
+		status character varying(10) COLLATE pg_catalog."default" NOT NULL,
+		attempts integer NOT NULL DEFAULT 0,
+		response_code integer NOT NULL DEFAULT 0,
+		error text COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+		next_attempt_at timestamp with time zone NOT NULL,
+		created_at timestamp with time zone NOT NULL,
+		delivered_at timestamp with time zone,
+		CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id),
+		CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (webhook_id)
+			REFERENCES public.webhooks (id) ON DELETE CASCADE
+	)
+	
+	TABLESPACE pg_default;
+
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx
+	ON public.webhook_deliveries (webhook_id, id);
+
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx
+	ON public.webhook_deliveries (next_attempt_at)
+	WHERE status = 'PENDING';
 `
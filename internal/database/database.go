@@ -2,32 +2,70 @@ package database
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"log"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const (
-	TransactionTypeAccrual    = "ACCRUAL"
-	TransactionTypeWithdrawal = "WITHDRAWAL"
-	dbExistError              = "42601"
+	TransactionTypeAccrual       = "ACCRUAL"
+	TransactionTypeWithdrawal    = "WITHDRAWAL"
+	TransactionTypeReversal      = "REVERSAL"
+	TransactionTypeExpiration    = "EXPIRATION"
+	TransactionTypeRefund        = "REFUND"
+	TransactionTypeWelcome       = "WELCOME"
+	TransactionTypeReferral      = "REFERRAL"
+	TransactionTypeTransferIn    = "TRANSFER_IN"
+	TransactionTypeTransferOut   = "TRANSFER_OUT"
+	TransactionTypeCampaign      = "CAMPAIGN"
+	TransactionTypeRedemption    = "REDEMPTION"
+	TransactionTypeVoucher       = "VOUCHER"
+	TransactionTypeBonusReversal = "BONUS_REVERSAL"
+	OrderSourceUser              = "USER"
+	OrderSourceAccrual           = "ACCRUAL"
+	OrderSourceRecheck           = "RECHECK"
+	OrderSourceAdmin             = "ADMIN"
+	OrderUploadAccepted          = "accepted"
+	OrderUploadDuplicate         = "already_uploaded"
+	OrderUploadAnotherUser       = "owned_by_another_user"
+	OrderUploadInvalid           = "invalid"
+	orderStatusProcessed         = "PROCESSED"
+	orderStatusInvalid           = "INVALID"
+	dbExistError                 = "42601"
 )
 
 type locker struct {
 	user, account sync.RWMutex
 }
 
+type dbConn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
 type databaseStorage struct {
-	conn   *pgx.Conn
-	dbUser string
-	locker
+	pool       *pgxpool.Pool
+	conn       dbConn
+	listenConn *pgx.Conn
+	listenURI  string
+	dbUser     string
+	policy     AccountPolicy
+	*locker
 }
 
 type CustomDateTime struct {
@@ -39,19 +77,67 @@ type Order struct {
 	UserLogin  string
 	Status     string
 	UploadedAt time.Time
+	Multiplier float32
 }
 
 type OrderWithAccrual struct {
-	ID         string         `json:"number"`
-	Status     string         `json:"status"`
-	Accrual    float32        `json:"accrual,omitempty"`
-	UploadedAt CustomDateTime `json:"uploaded_at"`
+	ID          string         `json:"number"`
+	Status      string         `json:"status"`
+	StatusLabel string         `json:"status_label,omitempty"`
+	Accrual     float32        `json:"accrual,omitempty"`
+	UploadedAt  CustomDateTime `json:"uploaded_at"`
+}
+
+type OrderCursor struct {
+	UploadedAt time.Time
+	ID         string
+}
+
+type OrderFilter struct {
+	Statuses   []string
+	From       *time.Time
+	To         *time.Time
+	Descending bool
+	Limit      int
+	After      *OrderCursor
+}
+
+type OrderPage struct {
+	Orders []OrderWithAccrual
+	Next   *OrderCursor
+}
+
+type OrderUploadResult struct {
+	OrderID string `json:"number"`
+	Result  string `json:"result"`
+}
+
+type OrderDetails struct {
+	OrderWithAccrual
+	Withdrawn   float32         `json:"withdrawn,omitempty"`
+	Refunded    float32         `json:"refunded,omitempty"`
+	WithdrawnAt *CustomDateTime `json:"withdrawn_at,omitempty"`
+}
+
+type OrderStatusChange struct {
+	OrderID     string         `json:"-"`
+	UserLogin   string         `json:"-"`
+	OldStatus   string         `json:"old_status,omitempty"`
+	NewStatus   string         `json:"status"`
+	StatusLabel string         `json:"status_label,omitempty"`
+	Accrual     float32        `json:"accrual,omitempty"`
+	Source      string         `json:"source"`
+	CreatedAt   CustomDateTime `json:"changed_at"`
 }
 
 type Account struct {
 	UserLogin string  `json:"-"`
 	Balance   float32 `json:"current"`
 	Withdrawn float32 `json:"withdrawn"`
+	Pending   float32 `json:"pending"`
+	Held      float32 `json:"held"`
+	Debt      float32 `json:"debt,omitempty"`
+	Expiring  float32 `json:"expiring"`
 }
 
 type Transaction struct {
@@ -60,23 +146,129 @@ type Transaction struct {
 	Type        string         `json:"-"`
 	Amount      float32        `json:"sum"`
 	CreatedAt   CustomDateTime `json:"processed_at"`
+	CampaignID  int64          `json:"-"`
+	MerchantID  string         `json:"-"`
+}
+
+type Withdrawal struct {
+	Transaction
+	Refunded float32 `json:"refunded,omitempty"`
+}
+
+type UserTier struct {
+	UserLogin   string
+	Tier        string
+	Accrued     float32
+	EvaluatedAt time.Time
+}
+
+type ReferralStats struct {
+	Code     string  `json:"code"`
+	Invited  int     `json:"invited"`
+	Rewarded int     `json:"rewarded"`
+	Earned   float32 `json:"earned"`
 }
 
 type Storager interface {
 	AddUser(user string, password string) error
 	GetUserPassword(login string) (string, error)
 
+	GetUserByReferralCode(code string) (string, error)
+	IsSelfReferral(user, referrer, ip string) (bool, error)
+	SetUserReferral(user, code, referrer, ip string) error
+	SetReferralCode(user, code string) error
+	GetReferralStats(user string) (*ReferralStats, error)
+	AddWelcomeBonus(user string, amount float32) error
+	RewardReferral(referee, orderNumber string, amount float32, limit int) (string, error)
+
 	AddOrder(user string, order string) error
-	GetOrders(user string) ([]OrderWithAccrual, error)
+	AddOrders(user string, orders []string) ([]OrderUploadResult, error)
+	GetOrders(user string, filter *OrderFilter) (*OrderPage, error)
+	GetOrder(orderID string) (*Order, error)
+	GetUserOrder(user, orderID string) (*OrderDetails, error)
+	GetOrderStatuses(user string, orderIDs []string) ([]OrderWithAccrual, error)
 	GetOrdersToProcess() ([]Order, error)
-	UpdateOrder(*Order, float32) error
+	GetOrdersToRecheck(since time.Time) ([]Order, error)
+	UpdateOrder(order *Order, previousStatus string, amount float32, source string) error
+	GetOrderHistory(orderID string) ([]OrderStatusChange, error)
+	WaitForNewOrder(ctx context.Context) (*Order, error)
 
 	GetTransactions(user, txType string) ([]Transaction, error)
+	GetUserTransactions(user string) ([]Transaction, error)
+	GetStatement(user string, filter *StatementFilter) (*StatementPage, error)
+	GetBalanceAt(user string, at *time.Time) (float32, error)
 	AddTransaction(transaction *Transaction) error
 	UpdateUserAccount(account *Account) error
+	Withdraw(transaction *Transaction, limits WithdrawalLimits) error
+	GetWithdrawals(user string) ([]Withdrawal, error)
+	Refund(orderNumber, merchant string, amount float32) (*Transaction, error)
+	ExpirePoints() error
+	ReleasePendingPoints() error
+
+	GetAccrualTotals(since time.Time) (map[string]float32, error)
+	GetUserAccrualTotal(user string, since time.Time) (float32, error)
+	SaveUserTiers(tiers []UserTier, evaluatedAt time.Time) error
+	GetUserTier(user string) (*UserTier, error)
+
+	CreateTransfer(transfer *Transfer, dailyLimit float32) error
+	ConfirmTransfer(user string, transferID int64, passwordConfirmed bool, dailyLimit float32) (*Transfer, error)
+	GetTransfers(user string) ([]Transfer, error)
+
+	CreateCampaign(campaign *Campaign) error
+	UpdateCampaign(campaign *Campaign) error
+	DeleteCampaign(campaignID int64) error
+	GetCampaigns() ([]Campaign, error)
+	GetCampaign(campaignID int64) (*Campaign, error)
+	GetActiveCampaigns(at time.Time) ([]Campaign, error)
+	GetUserRegisteredAt(user string) (time.Time, error)
+	IsFirstProcessedOrder(user, orderNumber string) (bool, error)
+	AddCampaignBonus(user, orderNumber string, campaignID int64, amount float32) error
+
+	CreateRewardItem(item *RewardItem) error
+	UpdateRewardItem(item *RewardItem) error
+	DeleteRewardItem(itemID int64) error
+	GetRewardItems(availableOnly bool) ([]RewardItem, error)
+	GetRewardItem(itemID int64) (*RewardItem, error)
+	Redeem(redemption *Redemption) error
+	GetRedemptions(user string) ([]Redemption, error)
+	UpdateRedemptionStatus(redemptionID int64, status string) (*Redemption, error)
+
+	CreateVoucherBatch(batch *VoucherBatch, codes []string) error
+	GetVoucherBatches() ([]VoucherBatch, error)
+	RedeemVoucher(user, code string) (*Voucher, error)
+	AddVoucherAttempt(user, ip string, success bool) error
+	CountFailedVoucherAttempts(user, ip string, since time.Time) (int, int, error)
+
+	CreateHold(hold *BalanceHold, limits WithdrawalLimits) error
+	GetHolds(user string) ([]BalanceHold, error)
+	CaptureHold(user string, holdID int64) error
+	VoidHold(user string, holdID int64) error
+	ExpireHolds() error
+	GetExpiringPoints(user string, period time.Duration) (float32, error)
 
 	GetUserAccount(user string) (*Account, error)
 
+	ReserveIdempotencyKey(record *IdempotencyRecord, expiredBefore time.Time) (*IdempotencyRecord, error)
+	CompleteIdempotencyKey(record *IdempotencyRecord) error
+	RenewIdempotencyKey(user, key, token string, lockedUntil time.Time) error
+	DeleteIdempotencyKey(user, key, token string) error
+	DeleteExpiredIdempotencyKeys(expiredBefore time.Time) error
+
+	AddEvent(event *Event) error
+	GetEventsAfter(user string, afterID int64, limit int) ([]Event, error)
+	DeleteExpiredEvents(expiredBefore time.Time) error
+
+	CreateWebhook(webhook *Webhook) error
+	GetWebhooks(user string) ([]Webhook, error)
+	GetWebhook(user string, webhookID int64) (*Webhook, error)
+	UpdateWebhook(webhook *Webhook) error
+	DeleteWebhook(user string, webhookID int64) error
+	AddWebhookDeliveries(event *Event) (int64, error)
+	GetDueWebhookDeliveries(at time.Time, limit int) ([]WebhookDelivery, error)
+	UpdateWebhookDelivery(delivery *WebhookDelivery) error
+	RecordWebhookResult(webhookID int64, success bool, disableAfter int) (bool, error)
+	GetWebhookDeliveries(webhookID int64, limit int) ([]WebhookDelivery, error)
+
 	Close()
 }
 
@@ -96,15 +288,16 @@ func (c *CustomDateTime) MarshalJSON() ([]byte, error) {
 	return []byte(fmt.Sprintf(`"%s"`, c.Time.Format(time.RFC3339))), nil
 }
 
-func NewDatabaseStorage(ctx context.Context, databaseURI string) Storager {
-	dbStorage := &databaseStorage{}
+func NewDatabaseStorage(ctx context.Context, databaseURI string, policy AccountPolicy) Storager {
+	dbStorage := &databaseStorage{policy: policy, locker: &locker{}}
 
 	var err error
-	dbStorage.conn, err = pgx.Connect(ctx, databaseURI)
+	dbStorage.pool, err = pgxpool.New(ctx, databaseURI)
 	if err != nil {
 		log.Fatal(err)
 		return dbStorage
 	}
+	dbStorage.conn = dbStorage.pool
 
 	dbCfg := strings.Split(databaseURI, ":")
 	if len(dbCfg) < 2 {
@@ -124,6 +317,13 @@ func NewDatabaseStorage(ctx context.Context, databaseURI string) Storager {
 		log.Fatal(err)
 	}
 
+	dbStorage.listenURI = databaseURI
+
+	err = dbStorage.initListener(ctx)
+	if err != nil {
+		log.Println("Ошибка при подписке на уведомления о новых заказах, подписка будет повторена:", err)
+	}
+
 	return dbStorage
 }
 
@@ -145,11 +345,21 @@ func (s *databaseStorage) init(ctx context.Context) error {
 		return err
 	}
 
+	_, err = s.conn.Exec(ctx, sqlMigrateTableUsers)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.conn.Exec(ctx, sqlCreateTableOrders)
 	if err != nil {
 		return err
 	}
 
+	_, err = s.conn.Exec(ctx, sqlMigrateTableOrders)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.conn.Exec(ctx, sqlCreateTableAccounts)
 	if err != nil {
 		return err
@@ -160,26 +370,158 @@ func (s *databaseStorage) init(ctx context.Context) error {
 		return err
 	}
 
+	_, err = s.conn.Exec(ctx, sqlMigrateTableAccounts)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.conn.Exec(ctx, sqlCreateTableTransactions)
 	if err != nil {
 		return err
 	}
 
+	_, err = s.conn.Exec(ctx, sqlMigrateTableTransactions)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableOrderHistory)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableAccrualLots)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlMigrateAccrualLots)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableBalanceHolds)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableUserTiers)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableTransfers)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableCampaigns)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableRewards)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableVouchers)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableIdempotencyKeys)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlMigrateTableIdempotencyKeys)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableEvents)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, sqlCreateTableWebhooks)
+	if err != nil {
+		return err
+	}
+
 	log.Println("Таблицы успешно инициализированы в БД")
 	return nil
 }
 
-func (s *databaseStorage) Close() {
-	if s.conn == nil {
-		return
+func (s *databaseStorage) initListener(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, s.listenURI)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(ctx, queryListenNewOrders)
+	if err != nil {
+		closeErr := conn.Close(ctx)
+		if closeErr != nil {
+			log.Println(closeErr)
+		}
+
+		return err
+	}
+
+	s.listenConn = conn
+
+	log.Println("Подписка на уведомления о новых заказах успешно оформлена")
+	return nil
+}
+
+func (s *databaseStorage) inTransaction(fn func(tx *databaseStorage) error) (err error) {
+	if _, ok := s.conn.(pgx.Tx); ok {
+		return fn(s)
 	}
 
 	ctx := context.Background()
-	err := s.conn.Close(ctx)
+
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		log.Println(err)
+		log.Println("Ошибка при открытии транзакции БД:", err)
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit(ctx)
+			return
+		}
+
+		rollbackErr := tx.Rollback(ctx)
+		if rollbackErr != nil {
+			log.Println("Ошибка при откате транзакции БД:", rollbackErr)
+		}
+	}()
+
+	txStorage := *s
+	txStorage.conn = tx
+
+	return fn(&txStorage)
+}
+
+func (s *databaseStorage) Close() {
+	ctx := context.Background()
+
+	if s.listenConn != nil {
+		err := s.listenConn.Close(ctx)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	if s.pool == nil {
 		return
 	}
+
+	s.pool.Close()
 }
 
 func (s *databaseStorage) AddUser(user string, password string) error {
@@ -234,7 +576,23 @@ func (s *databaseStorage) AddOrder(user, order string) error {
 	ctx := context.Background()
 	var pgErr *pgconn.PgError
 
-	ct, err := s.conn.Exec(ctx, queryInsertOrder, order, user, time.Now())
+	uploaded := time.Now()
+	err := s.inTransaction(func(tx *databaseStorage) error {
+		ct, err := tx.conn.Exec(ctx, queryInsertOrder, order, user, uploaded)
+		if err != nil {
+			return err
+		}
+
+		log.Println("Добавлено записей заказов в таблицу БД:", ct.RowsAffected())
+
+		return tx.addOrderHistory(&OrderStatusChange{
+			OrderID:   order,
+			UserLogin: user,
+			NewStatus: "NEW",
+			Source:    OrderSourceUser,
+			CreatedAt: CustomDateTime{Time: uploaded},
+		})
+	})
 	if err != nil && !errors.As(err, &pgErr) {
 		log.Println("Ошибка при добавлении заказа '"+order+"' под пользователем '"+user+"' в БД:", err)
 		return err
@@ -265,14 +623,225 @@ func (s *databaseStorage) AddOrder(user, order string) error {
 
 	}
 
-	log.Println("Добавлено записей заказов в таблицу БД:", ct.RowsAffected())
+	s.notifyNewOrder(&Order{ID: order, UserLogin: user, Status: "NEW", UploadedAt: uploaded})
 	return nil
 }
 
-func (s *databaseStorage) GetOrders(user string) ([]OrderWithAccrual, error) {
+func (s *databaseStorage) AddOrders(user string, orders []string) ([]OrderUploadResult, error) {
+	log.Printf("Добавление в БД %v заказов для пользователя '%v'\n", len(orders), user)
+
+	result := make([]OrderUploadResult, 0, len(orders))
+
+	err := s.inTransaction(func(tx *databaseStorage) error {
+		ctx := context.Background()
+
+		rows, err := tx.conn.Query(ctx, queryInsertOrders, orders, user, time.Now(), OrderSourceUser)
+		if err != nil {
+			log.Println("Ошибка при добавлении заказов под пользователем '"+user+"' в БД:", err)
+			return err
+		}
+
+		existing := make([]string, 0)
+
+		for rows.Next() {
+			var upload OrderUploadResult
+			var accepted bool
+			err = rows.Scan(&upload.OrderID, &accepted)
+			if err != nil {
+				rows.Close()
+				log.Println("Ошибка при считывании результата добавления заказа:", err)
+				return err
+			}
+
+			if accepted {
+				upload.Result = OrderUploadAccepted
+			} else {
+				existing = append(existing, upload.OrderID)
+			}
+
+			result = append(result, upload)
+		}
+
+		rows.Close()
+
+		err = rows.Err()
+		if err != nil {
+			log.Println("Ошибка при добавлении заказов под пользователем '"+user+"' в БД:", err)
+			return err
+		}
+
+		if len(existing) == 0 {
+			return nil
+		}
+
+		owners, err := tx.getOrderOwners(existing)
+		if err != nil {
+			return err
+		}
+
+		for i := range result {
+			if result[i].Result == OrderUploadAccepted {
+				continue
+			}
+
+			result[i].Result = OrderUploadAnotherUser
+			if owners[result[i].OrderID] == user {
+				result[i].Result = OrderUploadDuplicate
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Обработано номеров заказов при пакетной загрузке:", len(result))
+	return result, nil
+}
+
+func (s *databaseStorage) getOrderOwners(orders []string) (map[string]string, error) {
 	ctx := context.Background()
 
-	rows, err := s.conn.Query(ctx, queryGetOrdersByUser, user)
+	rows, err := s.conn.Query(ctx, queryGetOrderOwners, orders)
+	if err != nil {
+		log.Println("Ошибка при запросе владельцев заказов:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	owners := make(map[string]string, len(orders))
+
+	for rows.Next() {
+		var id, owner string
+		err = rows.Scan(&id, &owner)
+		if err != nil {
+			log.Println("Ошибка при считывании владельца заказа:", err)
+			return nil, err
+		}
+
+		owners[id] = owner
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании владельцев заказов:", err)
+		return nil, err
+	}
+
+	return owners, nil
+}
+
+func (s *databaseStorage) notifyNewOrder(order *Order) {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		log.Println("Ошибка при формировании уведомления о новом заказе '"+order.ID+"':", err)
+		return
+	}
+
+	_, err = s.conn.Exec(context.Background(), queryNotifyNewOrder, string(payload))
+	if err != nil {
+		log.Println("Ошибка при отправке уведомления о новом заказе '"+order.ID+"':", err)
+	}
+}
+
+func (s *databaseStorage) WaitForNewOrder(ctx context.Context) (*Order, error) {
+	if s.listenConn == nil || s.listenConn.IsClosed() {
+		log.Println("Восстановление подписки на уведомления о новых заказах")
+
+		err := s.initListener(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	notification, err := s.listenConn.WaitForNotification(ctx)
+	if err != nil && ctx.Err() == nil {
+		closeErr := s.listenConn.Close(context.Background())
+		if closeErr != nil {
+			log.Println(closeErr)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var order Order
+	err = json.Unmarshal([]byte(notification.Payload), &order)
+	if err != nil {
+		log.Println("Ошибка при разборе уведомления о новом заказе:", err)
+		return nil, err
+	}
+
+	log.Printf("Получено уведомление о новом заказе '%v' пользователя '%v'\n", order.ID, order.UserLogin)
+	return &order, nil
+}
+
+func encodeCursor(t time.Time, key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(t.UnixNano(), 10) + ":" + key))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", errors.New("курсор страницы передан в неправильном формате")
+	}
+
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return time.Time{}, "", errors.New("курсор страницы передан в неправильном формате")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", errors.New("курсор страницы передан в неправильном формате")
+	}
+
+	return time.Unix(0, nanos), parts[1], nil
+}
+
+func (c *OrderCursor) String() string {
+	return encodeCursor(c.UploadedAt, c.ID)
+}
+
+func ParseOrderCursor(cursor string) (*OrderCursor, error) {
+	uploadedAt, id, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderCursor{UploadedAt: uploadedAt, ID: id}, nil
+}
+
+func (s *databaseStorage) GetOrders(user string, filter *OrderFilter) (*OrderPage, error) {
+	ctx := context.Background()
+
+	query := queryGetOrdersByUser
+	if filter.Descending {
+		query = queryGetOrdersByUserDesc
+	}
+
+	var statuses []string
+	if len(filter.Statuses) > 0 {
+		statuses = filter.Statuses
+	}
+
+	var afterUploadedAt *time.Time
+	var afterID string
+	if filter.After != nil {
+		afterUploadedAt = &filter.After.UploadedAt
+		afterID = filter.After.ID
+	}
+
+	var limit *int
+	if filter.Limit > 0 {
+		l := filter.Limit + 1
+		limit = &l
+	}
+
+	rows, err := s.conn.Query(ctx, query, user, statuses, filter.From, filter.To, afterUploadedAt, afterID, limit)
 	if err != nil {
 		log.Println("Ошибка при запросе списка заказов пользователя:", err)
 		return nil, err
@@ -299,6 +868,91 @@ func (s *databaseStorage) GetOrders(user string) ([]OrderWithAccrual, error) {
 		return nil, err
 	}
 
+	page := OrderPage{Orders: result}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		page.Orders = result[:filter.Limit]
+		last := page.Orders[len(page.Orders)-1]
+		page.Next = &OrderCursor{UploadedAt: last.UploadedAt.Time, ID: last.ID}
+	}
+
+	return &page, nil
+}
+
+func (s *databaseStorage) GetOrder(orderID string) (*Order, error) {
+	ctx := context.Background()
+	var order Order
+
+	row := s.conn.QueryRow(ctx, queryGetOrderByID, orderID)
+	err := row.Scan(&order.ID, &order.UserLogin, &order.Status, &order.UploadedAt, &order.Multiplier)
+
+	if err != nil && err == pgx.ErrNoRows {
+		log.Println("Заказ " + orderID + " не найден")
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании заказа "+orderID+" из БД:", err)
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (s *databaseStorage) GetUserOrder(user, orderID string) (*OrderDetails, error) {
+	ctx := context.Background()
+	var order OrderDetails
+	var withdrawnAt *time.Time
+
+	row := s.conn.QueryRow(ctx, queryGetUserOrder, user, orderID)
+	err := row.Scan(&order.ID, &order.Status, &order.Accrual, &order.UploadedAt.Time, &order.Withdrawn, &order.Refunded, &withdrawnAt)
+
+	if err != nil && err == pgx.ErrNoRows {
+		log.Println("Заказ " + orderID + " пользователя " + user + " не найден")
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании заказа "+orderID+" пользователя "+user+" из БД:", err)
+		return nil, err
+	}
+
+	if withdrawnAt != nil {
+		order.WithdrawnAt = &CustomDateTime{Time: *withdrawnAt}
+	}
+
+	return &order, nil
+}
+
+func (s *databaseStorage) GetOrderStatuses(user string, orderIDs []string) ([]OrderWithAccrual, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetOrderStatuses, user, orderIDs)
+	if err != nil {
+		log.Println("Ошибка при запросе статусов заказов пользователя:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]OrderWithAccrual, 0, len(orderIDs))
+
+	for rows.Next() {
+		var order OrderWithAccrual
+		err = rows.Scan(&order.ID, &order.Status, &order.Accrual, &order.UploadedAt.Time)
+		if err != nil {
+			log.Println("Ошибка при считывании статуса заказа пользователя:", err)
+			return nil, err
+		}
+
+		result = append(result, order)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании статусов заказов пользователя:", err)
+		return nil, err
+	}
+
 	return result, nil
 }
 
@@ -335,65 +989,257 @@ func (s *databaseStorage) GetOrdersToProcess() ([]Order, error) {
 	return result, nil
 }
 
-func (s *databaseStorage) UpdateOrder(order *Order, amount float32) error {
-	log.Printf("Обновление заказа '%v' пользователя '%v', статус '%v'\n", order.ID, order.UserLogin, order.Status)
+func (s *databaseStorage) GetOrdersToRecheck(since time.Time) ([]Order, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetOrdersToRecheck, since)
+	if err != nil {
+		log.Println("Ошибка при запросе заказов для повторной проверки начисления баллов:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]Order, 0)
+
+	for rows.Next() {
+		var order Order
+		err = rows.Scan(&order.ID, &order.UserLogin, &order.Status, &order.UploadedAt, &order.Multiplier)
+		if err != nil {
+			log.Println("Ошибка при считывании записи заказа пользователя из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, order)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании записей заказов пользователя из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) UpdateOrder(order *Order, previousStatus string, amount float32, source string) error {
+	log.Printf("Обновление заказа '%v' пользователя '%v', статус '%v' -> '%v'\n", order.ID, order.UserLogin, previousStatus, order.Status)
 
 	s.locker.account.Lock()
 	defer s.locker.account.Unlock()
 
-	account, err := s.GetUserAccount(order.UserLogin)
-	if err != nil {
-		log.Println("Ошибка при обновлении заказа "+order.ID+":", err)
-		return err
+	return s.inTransaction(func(tx *databaseStorage) error {
+		account, err := tx.GetUserAccount(order.UserLogin)
+		if err != nil {
+			log.Println("Ошибка при обновлении заказа "+order.ID+":", err)
+			return err
+		}
+
+		credited, err := tx.getOrderAccrual(order.ID)
+		if err != nil {
+			log.Println("Ошибка при обновлении заказа "+order.ID+":", err)
+			return err
+		}
+
+		ctx := context.Background()
+
+		ct, err := tx.conn.Exec(ctx, queryUpdateOrder, order.ID, previousStatus, order.Status, order.Multiplier)
+		if err != nil {
+			log.Println("Ошибка при обновлении заказа "+order.ID+":", err)
+			return err
+		}
+
+		if ct.RowsAffected() == 0 {
+			err = NewDBOrderStatusError(order.ID, previousStatus, order.Status, errors.New("заказ "+order.ID+" не найден в статусе "+previousStatus))
+			log.Println("Ошибка при обновлении заказа "+order.ID+":", err)
+			return err
+		}
+
+		var delta float32
+		switch order.Status {
+		case orderStatusProcessed:
+			delta = amount - credited
+		case orderStatusInvalid:
+			delta = -credited
+		}
+
+		if previousStatus == order.Status && delta > -amountPrecision && delta < amountPrecision {
+			log.Println("Заказ " + order.ID + " не изменился после повторной проверки")
+			return nil
+		}
+
+		err = tx.addOrderHistory(&OrderStatusChange{
+			OrderID:   order.ID,
+			UserLogin: order.UserLogin,
+			OldStatus: previousStatus,
+			NewStatus: order.Status,
+			Accrual:   amount,
+			Source:    source,
+			CreatedAt: CustomDateTime{Time: time.Now()},
+		})
+		if err != nil {
+			return err
+		}
+
+		if delta > -amountPrecision && delta < amountPrecision {
+			log.Println("Заказ " + order.ID + " успешно обновлён")
+			return tx.reverseOrderBonuses(order)
+		}
+
+		transaction := &Transaction{
+			OrderNumber: order.ID,
+			UserLogin:   order.UserLogin,
+			Type:        TransactionTypeAccrual,
+			Amount:      delta,
+			CreatedAt:   CustomDateTime{Time: time.Now()},
+		}
+
+		if delta > 0 {
+			err = tx.creditWithHold(account, order.ID, delta, transaction.CreatedAt.Time)
+		} else {
+			transaction.Type = TransactionTypeReversal
+			transaction.Amount = -delta
+			log.Printf("Отмена начисления по заказу '%v' пользователя '%v' на сумму '%v', политика '%v'\n", order.ID, order.UserLogin, -delta, tx.policy.ReversalPolicy)
+
+			err = tx.reverseOrderAccrual(account, order.ID, -delta)
+		}
+
+		if err != nil {
+			log.Println("Ошибка при обновлении заказа "+order.ID+":", err)
+			return err
+		}
+
+		_, err = tx.conn.Exec(ctx, queryUpdateUserAccount, account.UserLogin, account.Balance, account.Withdrawn, account.Debt, account.Pending, account.Held)
+		if err != nil {
+			log.Println("Ошибка при обновлении баланса пользователя при обработке заказа "+order.ID+":", err)
+			return err
+		}
+
+		err = tx.AddTransaction(transaction)
+		if err != nil {
+			log.Println("Ошибка при создании транзакции по заказу "+order.ID+":", err)
+			return err
+		}
+
+		log.Println("Заказ " + order.ID + " успешно обновлён")
+		return tx.reverseOrderBonuses(order)
+	})
+}
+
+func (s *databaseStorage) reverseOrderBonuses(order *Order) error {
+	if order.Status != orderStatusInvalid {
+		return nil
 	}
 
-	transaction, err := s.getTransaction(order.ID)
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetOrderBonuses, order.ID)
 	if err != nil {
-		log.Println("Ошибка при обновлении заказа "+order.ID+":", err)
+		log.Println("Ошибка при запросе бонусов по заказу "+order.ID+":", err)
 		return err
 	}
 
-	if amount > 0 && transaction != nil && transaction.Type == TransactionTypeAccrual {
-		err = errors.New("Для заказа " + order.ID + " уже существует транзакция начисления от " + transaction.CreatedAt.String())
-		return err
-	}
+	bonuses := make([]Transaction, 0)
+	for rows.Next() {
+		var bonus Transaction
+		err = rows.Scan(&bonus.UserLogin, &bonus.Type, &bonus.CampaignID, &bonus.Amount)
+		if err != nil {
+			rows.Close()
+			log.Println("Ошибка при считывании бонуса по заказу "+order.ID+":", err)
+			return err
+		}
 
-	ctx := context.Background()
+		bonuses = append(bonuses, bonus)
+	}
+	rows.Close()
 
-	_, err = s.conn.Exec(ctx, queryUpdateOrder, order.ID, order.Status)
+	err = rows.Err()
 	if err != nil {
-		log.Println("Ошибка при обновлении заказа "+order.ID+":", err)
+		log.Println("Ошибка при считывании бонусов по заказу "+order.ID+":", err)
 		return err
 	}
 
-	if amount > 0 {
-		account.Balance += amount
+	for _, bonus := range bonuses {
+		log.Printf("Отмена бонуса '%v' по заказу '%v' пользователя '%v' на сумму '%v'\n", bonus.Type, order.ID, bonus.UserLogin, bonus.Amount)
 
-		transaction = &Transaction{
-			OrderNumber: order.ID,
-			UserLogin:   order.UserLogin,
-			Type:        TransactionTypeAccrual,
-			Amount:      amount,
-			CreatedAt:   CustomDateTime{Time: time.Now()},
+		account, err := s.GetUserAccount(bonus.UserLogin)
+		if err != nil {
+			return err
 		}
 
-		_, err = s.conn.Exec(ctx, queryUpdateUserAccount, account.UserLogin, account.Balance, account.Withdrawn)
+		err = s.reverseOrderAccrual(account, order.ID, bonus.Amount)
+		if err != nil {
+			return err
+		}
+
+		err = s.UpdateUserAccount(account)
 		if err != nil {
-			log.Println("Ошибка при обновлении баланса пользователя при обработке заказа "+order.ID+":", err)
 			return err
 		}
 
-		_, err = s.conn.Exec(ctx, queryInsertTransaction, transaction.OrderNumber, transaction.UserLogin, transaction.Type, transaction.Amount, transaction.CreatedAt.Time)
+		err = s.AddTransaction(&Transaction{
+			OrderNumber: order.ID,
+			UserLogin:   bonus.UserLogin,
+			Type:        TransactionTypeBonusReversal,
+			Amount:      bonus.Amount,
+			CreatedAt:   CustomDateTime{Time: time.Now()},
+			CampaignID:  bonus.CampaignID,
+		})
 		if err != nil {
-			log.Println("Ошибка при создании транзакции начисления обработке заказа "+order.ID+":", err)
 			return err
 		}
 	}
 
-	log.Println("Заказ " + order.ID + " успешно обновлён")
 	return nil
 }
 
+func (s *databaseStorage) addOrderHistory(change *OrderStatusChange) error {
+	ctx := context.Background()
+
+	_, err := s.conn.Exec(ctx, queryInsertOrderHistory, change.OrderID, change.UserLogin, change.OldStatus, change.NewStatus, change.Accrual, change.Source, change.CreatedAt.Time)
+	if err != nil {
+		log.Println("Ошибка при записи истории статусов заказа "+change.OrderID+":", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) GetOrderHistory(orderID string) ([]OrderStatusChange, error) {
+	log.Printf("Получение истории статусов заказа '%v'\n", orderID)
+
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetOrderHistory, orderID)
+	if err != nil {
+		log.Println("Ошибка при запросе истории статусов заказа:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]OrderStatusChange, 0)
+
+	for rows.Next() {
+		var change OrderStatusChange
+		err = rows.Scan(&change.OrderID, &change.UserLogin, &change.OldStatus, &change.NewStatus, &change.Accrual, &change.Source, &change.CreatedAt.Time)
+		if err != nil {
+			log.Println("Ошибка при считывании записи истории статусов заказа:", err)
+			return nil, err
+		}
+
+		result = append(result, change)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании записей истории статусов заказа:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (s *databaseStorage) GetUserAccount(user string) (*Account, error) {
 	log.Printf("Получение балльного счёта пользователя '%v'\n", user)
 
@@ -401,7 +1247,7 @@ func (s *databaseStorage) GetUserAccount(user string) (*Account, error) {
 	var account Account
 
 	row := s.conn.QueryRow(ctx, queryGetUserAccount, user)
-	err := row.Scan(&account.UserLogin, &account.Balance, &account.Withdrawn)
+	err := row.Scan(&account.UserLogin, &account.Balance, &account.Withdrawn, &account.Debt, &account.Pending, &account.Held)
 	if err != nil {
 		log.Println("Ошибка при считывании балльного счёта пользователя "+user+" из БД:", err)
 		return nil, err
@@ -411,11 +1257,11 @@ func (s *databaseStorage) GetUserAccount(user string) (*Account, error) {
 }
 
 func (s *databaseStorage) UpdateUserAccount(account *Account) error {
-	log.Printf("Обновление балльного счёта пользователя '%v', баланс '%v', всего списано '%v'\n", account.UserLogin, account.Balance, account.Withdrawn)
+	log.Printf("Обновление балльного счёта пользователя '%v', баланс '%v', всего списано '%v', задолженность '%v', ожидает зачисления '%v'\n", account.UserLogin, account.Balance, account.Withdrawn, account.Debt, account.Pending)
 
 	ctx := context.Background()
 
-	_, err := s.conn.Exec(ctx, queryUpdateUserAccount, account.UserLogin, account.Balance, account.Withdrawn)
+	_, err := s.conn.Exec(ctx, queryUpdateUserAccount, account.UserLogin, account.Balance, account.Withdrawn, account.Debt, account.Pending, account.Held)
 	if err != nil {
 		log.Println("Ошибка при обновлении балльного счёта пользователя:", err)
 		return err
@@ -424,26 +1270,20 @@ func (s *databaseStorage) UpdateUserAccount(account *Account) error {
 	return nil
 }
 
-func (s *databaseStorage) getTransaction(orderID string) (*Transaction, error) {
-	log.Printf("Получение транзакции по заказу '%v'\n", orderID)
+func (s *databaseStorage) getOrderAccrual(orderID string) (float32, error) {
+	log.Printf("Получение суммы начислений по заказу '%v'\n", orderID)
 
 	ctx := context.Background()
-	var transaction Transaction
-
-	row := s.conn.QueryRow(ctx, queryGetTransaction, orderID)
-	err := row.Scan(&transaction.OrderNumber, &transaction.UserLogin, &transaction.Type, &transaction.Amount, &transaction.CreatedAt.Time)
-
-	if err != nil && err == pgx.ErrNoRows {
-		log.Println("Транзакции по заказу " + orderID + " не найдены")
-		return nil, nil
-	}
+	var amount float32
 
+	row := s.conn.QueryRow(ctx, queryGetOrderAccrual, orderID)
+	err := row.Scan(&amount)
 	if err != nil {
-		log.Println("Ошибка при считывании транзакции по заказу "+orderID+" из БД:", err)
-		return nil, err
+		log.Println("Ошибка при считывании суммы начислений по заказу "+orderID+" из БД:", err)
+		return 0, err
 	}
 
-	return &transaction, nil
+	return amount, nil
 }
 
 func (s *databaseStorage) AddTransaction(transaction *Transaction) error {
@@ -451,7 +1291,7 @@ func (s *databaseStorage) AddTransaction(transaction *Transaction) error {
 
 	ctx := context.Background()
 
-	_, err := s.conn.Exec(ctx, queryInsertTransaction, transaction.OrderNumber, transaction.UserLogin, transaction.Type, transaction.Amount, transaction.CreatedAt.Time)
+	_, err := s.conn.Exec(ctx, queryInsertTransaction, transaction.OrderNumber, transaction.UserLogin, transaction.Type, transaction.Amount, transaction.CreatedAt.Time, transaction.CampaignID, transaction.MerchantID)
 	if err != nil {
 		log.Println("Ошибка при добавлении транзакции:", err)
 		return err
@@ -494,3 +1334,38 @@ func (s *databaseStorage) GetTransactions(user, txType string) ([]Transaction, e
 
 	return result, nil
 }
+
+func (s *databaseStorage) GetUserTransactions(user string) ([]Transaction, error) {
+	log.Printf("Получение всех транзакций пользователя '%v'\n", user)
+
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetUserTransactions, user)
+	if err != nil {
+		log.Println("Ошибка при запросе транзакций пользователя:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]Transaction, 0)
+
+	for rows.Next() {
+		var transaction Transaction
+		err = rows.Scan(&transaction.OrderNumber, &transaction.UserLogin, &transaction.Type, &transaction.Amount, &transaction.CreatedAt.Time, &transaction.CampaignID)
+		if err != nil {
+			log.Println("Ошибка при считывании записи транзакции из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, transaction)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании записей транзакций из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
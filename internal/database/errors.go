@@ -16,6 +16,92 @@ type DBOrderError struct {
 	DBError
 }
 
+type DBAccountError struct {
+	User              string
+	InsufficientFunds bool
+	Err               error
+}
+
+type DBHoldError struct {
+	HoldID    int64
+	User      string
+	NotFound  bool
+	Inactive  bool
+	Duplicate bool
+	Err       error
+}
+
+type DBRefundError struct {
+	Order             string
+	NotFound          bool
+	ExceedsWithdrawal bool
+	Err               error
+}
+
+type DBReferralError struct {
+	Code     string
+	NotFound bool
+	Err      error
+}
+
+type DBTransferError struct {
+	TransferID        int64
+	User              string
+	NotFound          bool
+	RecipientNotFound bool
+	Inactive          bool
+	InvalidPassword   bool
+	LimitExceeded     bool
+	Err               error
+}
+
+type DBCampaignError struct {
+	CampaignID int64
+	NotFound   bool
+	Err        error
+}
+
+type DBRewardError struct {
+	ItemID       int64
+	RedemptionID int64
+	NotFound     bool
+	Unavailable  bool
+	OutOfStock   bool
+	Inactive     bool
+	Err          error
+}
+
+type DBVoucherError struct {
+	Code      string
+	NotFound  bool
+	Redeemed  bool
+	Expired   bool
+	Duplicate bool
+	Err       error
+}
+
+type DBOrderStatusError struct {
+	Order     string
+	Status    string
+	NewStatus string
+	Err       error
+}
+
+type DBWithdrawalLimitError struct {
+	User    string
+	Daily   bool
+	Monthly bool
+	Limit   float32
+	Spent   float32
+	Err     error
+}
+
+type DBWebhookError struct {
+	WebhookID int64
+	NotFound  bool
+	Err       error
+}
+
 func (e DBError) Error() string {
 	if e.Duplicate {
 		return fmt.Sprintf("При попытке добавления записи в БД обнаружен дубликат. Ошибка: %v", e.Err)
@@ -40,6 +126,50 @@ func (e DBOrderError) Error() string {
 	return e.Err.Error()
 }
 
+func (e DBAccountError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBHoldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBRefundError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBReferralError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBTransferError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBCampaignError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBRewardError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBVoucherError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBWithdrawalLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBWebhookError) Error() string {
+	return e.Err.Error()
+}
+
+func (e DBOrderStatusError) Error() string {
+	return fmt.Sprintf("Статус заказа %v в БД отличается от ожидаемого %v, переход в статус %v отклонён. Ошибка: %v", e.Order, e.Status, e.NewStatus, e.Err)
+}
+
 func (e DBError) Is(target error) bool {
 	err, ok := target.(DBError)
 	if !ok {
@@ -108,3 +238,111 @@ func NewDBOrderError(order string, user string, anotherUser bool, duplicate bool
 		},
 	}
 }
+
+func NewDBOrderStatusError(order string, status string, newStatus string, err error) error {
+	return &DBOrderStatusError{
+		Order:     order,
+		Status:    status,
+		NewStatus: newStatus,
+		Err:       err,
+	}
+}
+
+func NewDBAccountError(user string, insufficientFunds bool, err error) error {
+	return &DBAccountError{
+		User:              user,
+		InsufficientFunds: insufficientFunds,
+		Err:               err,
+	}
+}
+
+func NewDBHoldError(holdID int64, user string, notFound, inactive, duplicate bool, err error) error {
+	return &DBHoldError{
+		HoldID:    holdID,
+		User:      user,
+		NotFound:  notFound,
+		Inactive:  inactive,
+		Duplicate: duplicate,
+		Err:       err,
+	}
+}
+
+func NewDBRefundError(order string, notFound, exceedsWithdrawal bool, err error) error {
+	return &DBRefundError{
+		Order:             order,
+		NotFound:          notFound,
+		ExceedsWithdrawal: exceedsWithdrawal,
+		Err:               err,
+	}
+}
+
+func NewDBReferralError(code string, notFound bool, err error) error {
+	return &DBReferralError{
+		Code:     code,
+		NotFound: notFound,
+		Err:      err,
+	}
+}
+
+func NewDBTransferError(transferID int64, user string, notFound, recipientNotFound, inactive, invalidPassword, limitExceeded bool, err error) error {
+	return &DBTransferError{
+		TransferID:        transferID,
+		User:              user,
+		NotFound:          notFound,
+		RecipientNotFound: recipientNotFound,
+		Inactive:          inactive,
+		InvalidPassword:   invalidPassword,
+		LimitExceeded:     limitExceeded,
+		Err:               err,
+	}
+}
+
+func NewDBCampaignError(campaignID int64, notFound bool, err error) error {
+	return &DBCampaignError{
+		CampaignID: campaignID,
+		NotFound:   notFound,
+		Err:        err,
+	}
+}
+
+func NewDBRewardError(itemID, redemptionID int64, notFound, unavailable, outOfStock, inactive bool, err error) error {
+	return &DBRewardError{
+		ItemID:       itemID,
+		RedemptionID: redemptionID,
+		NotFound:     notFound,
+		Unavailable:  unavailable,
+		OutOfStock:   outOfStock,
+		Inactive:     inactive,
+		Err:          err,
+	}
+}
+
+func NewDBVoucherError(code string, notFound, redeemed, expired, duplicate bool, err error) error {
+	return &DBVoucherError{
+		Code:      code,
+		NotFound:  notFound,
+		Redeemed:  redeemed,
+		Expired:   expired,
+		Duplicate: duplicate,
+		Err:       err,
+	}
+}
+
+func NewDBWithdrawalLimitError(user string, daily, monthly bool, limit, spent float32, err error) error {
+	return &DBWithdrawalLimitError{
+		User:    user,
+		Daily:   daily,
+		Monthly: monthly,
+		Limit:   limit,
+		Spent:   spent,
+		Err:     err,
+	}
+}
+
+func NewDBWebhookError(webhookID int64, notFound bool, err error) error {
+	return &DBWebhookError{
+		WebhookID: webhookID,
+		NotFound:  notFound,
+		Err:       err,
+	}
+}
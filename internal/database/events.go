@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type Event struct {
+	ID        int64
+	UserLogin string
+	Type      string
+	Payload   string
+	CreatedAt time.Time
+}
+
+func (s *databaseStorage) AddEvent(event *Event) error {
+	ctx := context.Background()
+
+	err := s.conn.QueryRow(ctx, queryInsertEvent, event.UserLogin, event.Type, event.Payload, event.CreatedAt).Scan(&event.ID)
+	if err != nil {
+		log.Println("Ошибка при сохранении события '"+event.Type+"' пользователя '"+event.UserLogin+"':", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) GetEventsAfter(user string, afterID int64, limit int) ([]Event, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetEventsAfter, user, afterID, limit)
+	if err != nil {
+		log.Println("Ошибка при запросе событий пользователя:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]Event, 0)
+
+	for rows.Next() {
+		var event Event
+		err = rows.Scan(&event.ID, &event.UserLogin, &event.Type, &event.Payload, &event.CreatedAt)
+		if err != nil {
+			log.Println("Ошибка при считывании события пользователя из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, event)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании событий пользователя из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) DeleteExpiredEvents(expiredBefore time.Time) error {
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryDeleteExpiredEvents, expiredBefore)
+	if err != nil {
+		log.Println("Ошибка при удалении устаревших событий:", err)
+		return err
+	}
+
+	if ct.RowsAffected() > 0 {
+		log.Println("Удалено устаревших событий:", ct.RowsAffected())
+	}
+
+	return nil
+}
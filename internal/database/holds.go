@@ -0,0 +1,331 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	HoldStatusActive   = "ACTIVE"
+	HoldStatusCaptured = "CAPTURED"
+	HoldStatusVoided   = "VOIDED"
+	HoldStatusExpired  = "EXPIRED"
+)
+
+type BalanceHold struct {
+	ID          int64          `json:"id"`
+	UserLogin   string         `json:"-"`
+	OrderNumber string         `json:"order"`
+	Amount      float32        `json:"sum"`
+	Status      string         `json:"status"`
+	StatusLabel string         `json:"status_label,omitempty"`
+	CreatedAt   CustomDateTime `json:"created_at"`
+	ExpiresAt   CustomDateTime `json:"expires_at"`
+}
+
+func (s *databaseStorage) CreateHold(hold *BalanceHold, limits WithdrawalLimits) error {
+	log.Printf("Резервирование баллов для заказа '%v', пользователь '%v', сумма '%v'\n", hold.OrderNumber, hold.UserLogin, hold.Amount)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		ctx := context.Background()
+
+		var withdrawn bool
+		err := tx.conn.QueryRow(ctx, queryCheckWithdrawalExists, hold.OrderNumber).Scan(&withdrawn)
+		if err != nil {
+			log.Println("Ошибка при проверке списаний по заказу "+hold.OrderNumber+":", err)
+			return err
+		}
+
+		if withdrawn {
+			return NewDBHoldError(0, hold.UserLogin, false, false, true, errors.New("по заказу "+hold.OrderNumber+" уже произведено списание баллов"))
+		}
+
+		err = tx.refreshUserLots(hold.UserLogin)
+		if err != nil {
+			return err
+		}
+
+		account, err := tx.GetUserAccount(hold.UserLogin)
+		if err != nil {
+			return err
+		}
+
+		if hold.Amount > account.Balance {
+			return NewDBAccountError(hold.UserLogin, true, errors.New("на счёте пользователя "+hold.UserLogin+" недостаточно средств ("+strconv.FormatFloat(float64(account.Balance), 'E', -1, 32)+") для резервирования "+strconv.FormatFloat(float64(hold.Amount), 'E', -1, 32)+" баллов"))
+		}
+
+		err = tx.checkWithdrawalLimits(hold.UserLogin, hold.Amount, limits)
+		if err != nil {
+			return err
+		}
+
+		var pgErr *pgconn.PgError
+		err = tx.conn.QueryRow(ctx, queryInsertHold, hold.UserLogin, hold.OrderNumber, hold.Amount, hold.CreatedAt.Time, hold.ExpiresAt.Time).Scan(&hold.ID)
+		if err != nil && errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return NewDBHoldError(0, hold.UserLogin, false, false, true, errors.New("для заказа "+hold.OrderNumber+" уже существует активный резерв баллов"))
+		}
+
+		if err != nil {
+			log.Println("Ошибка при добавлении резерва баллов в БД:", err)
+			return err
+		}
+
+		hold.Status = HoldStatusActive
+
+		allocations, err := tx.consumeLots(hold.UserLogin, "", hold.Amount)
+		if err != nil {
+			return err
+		}
+
+		for _, allocation := range allocations {
+			_, err = tx.conn.Exec(ctx, queryInsertHoldLot, hold.ID, allocation.lotID, allocation.amount)
+			if err != nil {
+				log.Println("Ошибка при сохранении партий баллов резерва:", err)
+				return err
+			}
+		}
+
+		account.Balance -= hold.Amount
+		account.Held += hold.Amount
+
+		return tx.UpdateUserAccount(account)
+	})
+}
+
+func (s *databaseStorage) GetHolds(user string) ([]BalanceHold, error) {
+	return s.getHolds(queryGetUserHolds, user)
+}
+
+func (s *databaseStorage) getHolds(query string, args ...any) ([]BalanceHold, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		log.Println("Ошибка при запросе резервов баллов:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]BalanceHold, 0)
+
+	for rows.Next() {
+		var hold BalanceHold
+		err = rows.Scan(&hold.ID, &hold.UserLogin, &hold.OrderNumber, &hold.Amount, &hold.Status, &hold.CreatedAt.Time, &hold.ExpiresAt.Time)
+		if err != nil {
+			log.Println("Ошибка при считывании резерва баллов из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, hold)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании резервов баллов из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) getActiveHold(user string, holdID int64) (*BalanceHold, error) {
+	ctx := context.Background()
+	var hold BalanceHold
+
+	row := s.conn.QueryRow(ctx, queryGetHold, holdID)
+	err := row.Scan(&hold.ID, &hold.UserLogin, &hold.OrderNumber, &hold.Amount, &hold.Status, &hold.CreatedAt.Time, &hold.ExpiresAt.Time)
+
+	if err != nil && err != pgx.ErrNoRows {
+		log.Println("Ошибка при считывании резерва баллов из БД:", err)
+		return nil, err
+	}
+
+	if err != nil || hold.UserLogin != user {
+		return nil, NewDBHoldError(holdID, user, true, false, false, errors.New("резерв баллов "+strconv.FormatInt(holdID, 10)+" не найден"))
+	}
+
+	if hold.Status != HoldStatusActive {
+		return nil, NewDBHoldError(holdID, user, false, true, false, errors.New("резерв баллов "+strconv.FormatInt(holdID, 10)+" уже завершён со статусом "+hold.Status))
+	}
+
+	if !hold.ExpiresAt.Time.After(time.Now()) {
+		return nil, NewDBHoldError(holdID, user, false, true, false, errors.New("срок действия резерва баллов "+strconv.FormatInt(holdID, 10)+" истёк"))
+	}
+
+	return &hold, nil
+}
+
+func (s *databaseStorage) finishHold(hold *BalanceHold, status string) error {
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryFinishHold, hold.ID, status, time.Now())
+	if err != nil {
+		log.Println("Ошибка при завершении резерва баллов:", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return NewDBHoldError(hold.ID, hold.UserLogin, false, true, false, errors.New("резерв баллов "+strconv.FormatInt(hold.ID, 10)+" уже завершён"))
+	}
+
+	hold.Status = status
+	return nil
+}
+
+func (s *databaseStorage) CaptureHold(user string, holdID int64) error {
+	log.Printf("Подтверждение резерва баллов '%v' пользователя '%v'\n", holdID, user)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		hold, err := tx.getActiveHold(user, holdID)
+		if err != nil {
+			return err
+		}
+
+		account, err := tx.GetUserAccount(user)
+		if err != nil {
+			return err
+		}
+
+		var withdrawn bool
+		err = tx.conn.QueryRow(context.Background(), queryCheckWithdrawalExists, hold.OrderNumber).Scan(&withdrawn)
+		if err != nil {
+			log.Println("Ошибка при проверке списаний по заказу "+hold.OrderNumber+":", err)
+			return err
+		}
+
+		if withdrawn {
+			return NewDBHoldError(hold.ID, user, false, false, true, errors.New("по заказу "+hold.OrderNumber+" уже произведено списание баллов"))
+		}
+
+		err = tx.finishHold(hold, HoldStatusCaptured)
+		if err != nil {
+			return err
+		}
+
+		transaction := Transaction{
+			OrderNumber: hold.OrderNumber,
+			UserLogin:   user,
+			Type:        TransactionTypeWithdrawal,
+			Amount:      hold.Amount,
+			CreatedAt:   CustomDateTime{Time: time.Now()},
+		}
+
+		err = tx.AddTransaction(&transaction)
+		if err != nil {
+			return err
+		}
+
+		account.Held -= hold.Amount
+		account.Withdrawn += hold.Amount
+
+		return tx.UpdateUserAccount(account)
+	})
+}
+
+func (s *databaseStorage) VoidHold(user string, holdID int64) error {
+	log.Printf("Отмена резерва баллов '%v' пользователя '%v'\n", holdID, user)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		hold, err := tx.getActiveHold(user, holdID)
+		if err != nil {
+			return err
+		}
+
+		return tx.releaseHold(hold, HoldStatusVoided)
+	})
+}
+
+func (s *databaseStorage) releaseHold(hold *BalanceHold, status string) error {
+	account, err := s.GetUserAccount(hold.UserLogin)
+	if err != nil {
+		return err
+	}
+
+	err = s.finishHold(hold, status)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetHoldLots, hold.ID)
+	if err != nil {
+		log.Println("Ошибка при запросе партий баллов резерва:", err)
+		return err
+	}
+
+	allocations := make([]lotAllocation, 0)
+	for rows.Next() {
+		var allocation lotAllocation
+		err = rows.Scan(&allocation.lotID, &allocation.amount)
+		if err != nil {
+			rows.Close()
+			log.Println("Ошибка при считывании партии баллов резерва:", err)
+			return err
+		}
+
+		allocations = append(allocations, allocation)
+	}
+	rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании партий баллов резерва:", err)
+		return err
+	}
+
+	for _, allocation := range allocations {
+		_, err = s.conn.Exec(ctx, queryRestoreLot, allocation.lotID, allocation.amount)
+		if err != nil {
+			log.Println("Ошибка при возврате баллов в партию:", err)
+			return err
+		}
+	}
+
+	account.Held -= hold.Amount
+	account.Balance += hold.Amount
+
+	log.Printf("Резерв баллов '%v' пользователя '%v' на сумму '%v' освобождён со статусом '%v'\n", hold.ID, hold.UserLogin, hold.Amount, status)
+	return s.UpdateUserAccount(account)
+}
+
+func (s *databaseStorage) ExpireHolds() error {
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	holds, err := s.getHolds(queryGetExpiredHolds, time.Now())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Найдено %v просроченных резервов баллов\n", len(holds))
+
+	for i := range holds {
+		err = s.inTransaction(func(tx *databaseStorage) error {
+			return tx.releaseHold(&holds[i], HoldStatusExpired)
+		})
+		if err != nil {
+			log.Println("Ошибка при освобождении просроченного резерва баллов:", err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type IdempotencyRecord struct {
+	UserLogin   string
+	Key         string
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	LockedUntil *time.Time
+	OwnerToken  string
+}
+
+func (s *databaseStorage) ReserveIdempotencyKey(record *IdempotencyRecord, expiredBefore time.Time) (*IdempotencyRecord, error) {
+	ctx := context.Background()
+
+	var user string
+	err := s.conn.QueryRow(ctx, queryReserveIdempotencyKey, record.UserLogin, record.Key, record.RequestHash, record.CreatedAt, expiredBefore, record.LockedUntil, record.OwnerToken).Scan(&user)
+	if err == nil {
+		log.Printf("Ключ идемпотентности '%v' пользователя '%v' зарезервирован\n", record.Key, record.UserLogin)
+		return nil, nil
+	}
+
+	if err != pgx.ErrNoRows {
+		log.Println("Ошибка при резервировании ключа идемпотентности '"+record.Key+"':", err)
+		return nil, err
+	}
+
+	var existing IdempotencyRecord
+	err = s.conn.QueryRow(ctx, queryGetIdempotencyKey, record.UserLogin, record.Key).Scan(&existing.UserLogin, &existing.Key, &existing.RequestHash,
+		&existing.StatusCode, &existing.ContentType, &existing.Body, &existing.CreatedAt, &existing.LockedUntil)
+	if err != nil {
+		log.Println("Ошибка при считывании ключа идемпотентности '"+record.Key+"':", err)
+		return nil, err
+	}
+
+	return &existing, nil
+}
+
+func (s *databaseStorage) CompleteIdempotencyKey(record *IdempotencyRecord) error {
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryCompleteIdempotencyKey, record.UserLogin, record.Key, record.StatusCode, record.ContentType, record.Body, record.OwnerToken)
+	if err != nil {
+		log.Println("Ошибка при сохранении ответа по ключу идемпотентности '"+record.Key+"':", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return errors.New("ключ идемпотентности '" + record.Key + "' больше не удерживается этим запросом")
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) RenewIdempotencyKey(user, key, token string, lockedUntil time.Time) error {
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryRenewIdempotencyKey, user, key, token, lockedUntil)
+	if err != nil {
+		log.Println("Ошибка при продлении блокировки ключа идемпотентности '"+key+"':", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return errors.New("ключ идемпотентности '" + key + "' больше не удерживается этим запросом")
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) DeleteIdempotencyKey(user, key, token string) error {
+	ctx := context.Background()
+
+	_, err := s.conn.Exec(ctx, queryDeleteIdempotencyKey, user, key, token)
+	if err != nil {
+		log.Println("Ошибка при удалении ключа идемпотентности '"+key+"':", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) DeleteExpiredIdempotencyKeys(expiredBefore time.Time) error {
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryDeleteExpiredIdempotencyKeys, expiredBefore)
+	if err != nil {
+		log.Println("Ошибка при удалении просроченных ключей идемпотентности:", err)
+		return err
+	}
+
+	if ct.RowsAffected() > 0 {
+		log.Println("Удалено просроченных ключей идемпотентности:", ct.RowsAffected())
+	}
+
+	return nil
+}
@@ -20,6 +20,47 @@ const (
  			id, user_login, status, uploaded
 		)
 	VALUES ($1, $2, 'NEW', $3)
+`
+	queryInsertOrders = `
+	WITH input AS (
+		SELECT id, ord
+		FROM unnest($1::text[]) WITH ORDINALITY AS t(id, ord)
+	), inserted AS (
+		INSERT INTO public.orders
+			(
+				id, user_login, status, uploaded
+			)
+		SELECT id, $2, 'NEW', $3
+		FROM input
+		ON CONFLICT (id) DO NOTHING
+		RETURNING id
+	), history AS (
+		INSERT INTO public.order_history
+			(
+				order_id, user_login, new_status, source, created_at
+			)
+		SELECT id, $2, 'NEW', $4, $3
+		FROM inserted
+	), notified AS (
+		SELECT pg_notify('new_orders', json_build_object('ID', id, 'UserLogin', $2::text, 'Status', 'NEW', 'UploadedAt', $3::timestamptz)::text)
+		FROM inserted
+	)
+	SELECT i.id, ins.id IS NOT NULL
+	FROM input AS i
+	LEFT JOIN inserted AS ins ON ins.id = i.id
+	CROSS JOIN (SELECT COUNT(*) FROM notified) AS n
+	ORDER BY i.ord
+`
+	queryGetOrderOwners = `
+	SELECT id, user_login
+	FROM public.orders
+	WHERE id = ANY($1)
+`
+	queryNotifyNewOrder = `
+	SELECT pg_notify('new_orders', $1)
+`
+	queryListenNewOrders = `
+	LISTEN new_orders
 `
 	queryGetOrderUserByID = `
 	SELECT user_login
@@ -29,21 +70,111 @@ const (
 	queryGetOrdersByUser = `
 	SELECT o.id, o.status, COALESCE(t.amount, 0), o.uploaded
 	FROM public.orders AS o 
-	LEFT JOIN public.transactions AS t 
-	ON t.order_number = o.id AND t.type = 'ACCRUAL'
+	LEFT JOIN LATERAL (
+		SELECT SUM(CASE WHEN type = 'REVERSAL' THEN -amount ELSE amount END) AS amount
+		FROM public.transactions
+		WHERE order_number = o.id AND user_login = $1 AND type IN ('ACCRUAL', 'REVERSAL')
+	) AS t
+	ON true
 	WHERE o.user_login = $1
-	ORDER BY o.uploaded ASC
+		AND ($2::text[] IS NULL OR o.status = ANY($2))
+		AND ($3::timestamptz IS NULL OR o.uploaded >= $3)
+		AND ($4::timestamptz IS NULL OR o.uploaded < $4)
+		AND ($5::timestamptz IS NULL OR (o.uploaded, o.id) > ($5, $6::text))
+	ORDER BY o.uploaded ASC, o.id ASC
+	LIMIT $7
+`
+	queryGetOrdersByUserDesc = `
+	SELECT o.id, o.status, COALESCE(t.amount, 0), o.uploaded
+	FROM public.orders AS o 
+	LEFT JOIN LATERAL (
+		SELECT SUM(CASE WHEN type = 'REVERSAL' THEN -amount ELSE amount END) AS amount
+		FROM public.transactions
+		WHERE order_number = o.id AND user_login = $1 AND type IN ('ACCRUAL', 'REVERSAL')
+	) AS t
+	ON true
+	WHERE o.user_login = $1
+		AND ($2::text[] IS NULL OR o.status = ANY($2))
+		AND ($3::timestamptz IS NULL OR o.uploaded >= $3)
+		AND ($4::timestamptz IS NULL OR o.uploaded < $4)
+		AND ($5::timestamptz IS NULL OR (o.uploaded, o.id) < ($5, $6::text))
+	ORDER BY o.uploaded DESC, o.id DESC
+	LIMIT $7
 `
 	queryGetOrdersToProcess = `
 	SELECT id, user_login, status, uploaded
 	FROM public.orders
 	WHERE status IN ('NEW', 'PROCESSING')
 	ORDER BY uploaded ASC
+`
+	queryGetOrdersToRecheck = `
+	SELECT o.id, o.user_login, o.status, o.uploaded, COALESCE(o.accrual_multiplier, 0)
+	FROM public.orders AS o
+	WHERE o.status = 'PROCESSED' AND EXISTS (
+		SELECT 1
+		FROM public.order_history AS h
+		WHERE h.order_id = o.id AND h.new_status = 'PROCESSED' AND h.old_status <> 'PROCESSED' AND h.created_at >= $1
+	)
+	ORDER BY o.uploaded ASC
+`
+	queryGetUserOrder = `
+	SELECT o.id, o.status, COALESCE(a.amount, 0), o.uploaded, COALESCE(w.amount, 0), COALESCE(r.amount, 0), w.created_at
+	FROM public.orders AS o
+	LEFT JOIN LATERAL (
+		SELECT SUM(CASE WHEN type = 'REVERSAL' THEN -amount ELSE amount END) AS amount
+		FROM public.transactions
+		WHERE order_number = o.id AND user_login = $1 AND type IN ('ACCRUAL', 'REVERSAL')
+	) AS a
+	ON true
+	LEFT JOIN LATERAL (
+		SELECT SUM(amount) AS amount, MAX(created_at) AS created_at
+		FROM public.transactions
+		WHERE order_number = o.id AND user_login = $1 AND type = 'WITHDRAWAL'
+	) AS w
+	ON true
+	LEFT JOIN LATERAL (
+		SELECT SUM(amount) AS amount
+		FROM public.transactions
+		WHERE order_number = o.id AND user_login = $1 AND type = 'REFUND'
+	) AS r
+	ON true
+	WHERE o.user_login = $1 AND o.id = $2
+`
+	queryGetOrderStatuses = `
+	SELECT o.id, o.status, COALESCE(t.amount, 0), o.uploaded
+	FROM public.orders AS o
+	LEFT JOIN LATERAL (
+		SELECT SUM(CASE WHEN type = 'REVERSAL' THEN -amount ELSE amount END) AS amount
+		FROM public.transactions
+		WHERE order_number = o.id AND user_login = $1 AND type IN ('ACCRUAL', 'REVERSAL')
+	) AS t
+	ON true
+	WHERE o.user_login = $1 AND o.id = ANY($2)
+	ORDER BY o.uploaded ASC, o.id ASC
+`
+	queryGetOrderByID = `
+	SELECT id, user_login, status, uploaded, COALESCE(accrual_multiplier, 0)
+	FROM public.orders
+	WHERE id = $1
 `
 	queryUpdateOrder = `
 	UPDATE public.orders
-	SET status = $2
-	WHERE id = $1
+	SET status = $3, accrual_multiplier = COALESCE(NULLIF($4::real, 0), accrual_multiplier)
+	WHERE id = $1 AND status = $2
+`
+
+	queryInsertOrderHistory = `
+	INSERT INTO public.order_history
+		(
+			order_id, user_login, old_status, new_status, accrual, source, created_at
+		)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+	queryGetOrderHistory = `
+	SELECT order_id, user_login, old_status, new_status, accrual, source, created_at
+	FROM public.order_history
+	WHERE order_id = $1
+	ORDER BY created_at ASC, id ASC
 `
 
 	queryInsertUserAccount = `
@@ -54,30 +185,658 @@ const (
 	VALUES ($1, $2, $3)
 `
 	queryGetUserAccount = `
-	SELECT user_login, balance, withdrawn
+	SELECT user_login, balance, withdrawn, debt, pending, held
 	FROM public.accounts
 	WHERE user_login = $1
 `
 	queryUpdateUserAccount = `
 	UPDATE public.accounts
-	SET balance = $2, withdrawn = $3
+	SET balance = $2, withdrawn = $3, debt = $4, pending = $5, held = $6
 	WHERE user_login = $1
 `
 
 	queryInsertTransaction = `
 	INSERT INTO public.transactions
-	( order_number, user_login, type, amount, created_at)
-	VALUES ($1, $2, $3, $4, $5)
+	( order_number, user_login, type, amount, created_at, campaign_id, merchant_id)
+	VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, ''))
 `
-	queryGetTransaction = `
-	SELECT order_number, user_login, type, amount, created_at
+	queryGetOrderAccrual = `
+	SELECT COALESCE(SUM(CASE WHEN type = 'REVERSAL' THEN -amount ELSE amount END), 0)
 	FROM  public.transactions
-	WHERE order_number = $1
+	WHERE order_number = $1 AND type IN ('ACCRUAL', 'REVERSAL')
 `
 	queryGetTransactions = `
 	SELECT order_number, user_login, type, amount, created_at
 	FROM  public.transactions
 	WHERE user_login = $1 AND type = $2
 	ORDER BY created_at ASC
+`
+	queryGetStatement = `
+	WITH ledger AS (
+		SELECT id, order_number, type, created_at, COALESCE(campaign_id, 0) AS campaign_id,
+			CASE WHEN type IN ('WITHDRAWAL', 'REVERSAL', 'EXPIRATION', 'TRANSFER_OUT', 'REDEMPTION', 'BONUS_REVERSAL') THEN -amount ELSE amount END AS amount
+		FROM public.transactions
+		WHERE user_login = $1
+	), statement AS (
+		SELECT id, order_number, type, created_at, campaign_id, amount,
+			ROUND(SUM(amount::numeric) OVER (ORDER BY created_at ASC, id ASC ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW), 2) AS ledger_total
+		FROM ledger
+	)
+	SELECT id, order_number, type, amount, ledger_total::real, created_at, campaign_id
+	FROM statement
+	WHERE ($2::text[] IS NULL OR type = ANY($2))
+		AND ($3::timestamptz IS NULL OR created_at >= $3)
+		AND ($4::timestamptz IS NULL OR created_at < $4)
+		AND ($5::timestamptz IS NULL OR (created_at, id) > ($5, $6::bigint))
+	ORDER BY created_at ASC, id ASC
+	LIMIT $7
+`
+
+	queryGetBalanceAt = `
+	SELECT COALESCE(ROUND(SUM(CASE WHEN type IN ('WITHDRAWAL', 'REVERSAL', 'EXPIRATION', 'TRANSFER_OUT', 'REDEMPTION', 'BONUS_REVERSAL') THEN -amount ELSE amount END::numeric), 2), 0)::real
+	FROM public.transactions
+	WHERE user_login = $1 AND ($2::timestamptz IS NULL OR created_at < $2)
+`
+
+	queryGetUserTransactions = `
+	SELECT order_number, user_login, type, amount, created_at, COALESCE(campaign_id, 0)
+	FROM  public.transactions
+	WHERE user_login = $1
+	ORDER BY created_at ASC, id ASC
+`
+
+	queryInsertLot = `
+	INSERT INTO public.accrual_lots
+		(
+			user_login, order_number, amount, remaining, created_at, expires_at, available_at, released
+		)
+	VALUES ($1, $2, $3, $3, $4, $5, $6, $7)
+`
+	queryGetOrderLots = `
+	SELECT id, order_number, remaining
+	FROM public.accrual_lots
+	WHERE user_login = $1 AND order_number = $2 AND remaining > 0 AND released
+	ORDER BY created_at DESC, id DESC
+`
+	queryGetOrderPendingLots = `
+	SELECT id, order_number, remaining
+	FROM public.accrual_lots
+	WHERE user_login = $1 AND order_number = $2 AND remaining > 0 AND NOT released
+	ORDER BY created_at DESC, id DESC
+`
+	queryGetActiveLots = `
+	SELECT id, order_number, remaining
+	FROM public.accrual_lots
+	WHERE user_login = $1 AND remaining > 0 AND released AND (expires_at IS NULL OR expires_at > $2)
+	ORDER BY created_at ASC, id ASC
+`
+	queryGetExpiredLots = `
+	SELECT id, order_number, remaining
+	FROM public.accrual_lots
+	WHERE user_login = $1 AND remaining > 0 AND released AND expires_at <= $2
+	ORDER BY expires_at ASC, id ASC
+`
+	queryGetReleasableLots = `
+	SELECT id, order_number, remaining
+	FROM public.accrual_lots
+	WHERE user_login = $1 AND NOT released AND available_at <= $2
+	ORDER BY available_at ASC, id ASC
+`
+	queryReleaseLot = `
+	UPDATE public.accrual_lots
+	SET released = true
+	WHERE id = $1
+`
+	queryGetUsersWithReleasableLots = `
+	SELECT DISTINCT user_login
+	FROM public.accrual_lots
+	WHERE NOT released AND available_at <= $1
+`
+	queryUpdateLotRemaining = `
+	UPDATE public.accrual_lots
+	SET remaining = $2
+	WHERE id = $1
+`
+	queryGetUsersWithExpiredLots = `
+	SELECT DISTINCT user_login
+	FROM public.accrual_lots
+	WHERE remaining > 0 AND released AND expires_at <= $1
+`
+	queryGetExpiringPoints = `
+	SELECT COALESCE(SUM(remaining), 0)
+	FROM public.accrual_lots
+	WHERE user_login = $1 AND remaining > 0 AND expires_at > $2 AND expires_at <= $3
+`
+	queryRestoreLot = `
+	UPDATE public.accrual_lots
+	SET remaining = remaining + $2
+	WHERE id = $1
+`
+
+	queryCheckWithdrawalExists = `
+	SELECT EXISTS (
+		SELECT 1
+		FROM public.transactions
+		WHERE order_number = $1 AND type = 'WITHDRAWAL'
+	)
+`
+	queryInsertHold = `
+	INSERT INTO public.balance_holds
+		(
+			user_login, order_number, amount, status, created_at, expires_at
+		)
+	VALUES ($1, $2, $3, 'ACTIVE', $4, $5)
+	RETURNING id
+`
+	queryGetHold = `
+	SELECT id, user_login, order_number, amount, status, created_at, expires_at
+	FROM public.balance_holds
+	WHERE id = $1
+`
+	queryGetUserHolds = `
+	SELECT id, user_login, order_number, amount, status, created_at, expires_at
+	FROM public.balance_holds
+	WHERE user_login = $1 AND status = 'ACTIVE'
+	ORDER BY created_at ASC, id ASC
+`
+	queryGetExpiredHolds = `
+	SELECT id, user_login, order_number, amount, status, created_at, expires_at
+	FROM public.balance_holds
+	WHERE status = 'ACTIVE' AND expires_at <= $1
+	ORDER BY expires_at ASC, id ASC
+`
+	queryFinishHold = `
+	UPDATE public.balance_holds
+	SET status = $2, finished_at = $3
+	WHERE id = $1 AND status = 'ACTIVE'
+`
+	queryInsertHoldLot = `
+	INSERT INTO public.balance_hold_lots
+		(
+			hold_id, lot_id, amount
+		)
+	VALUES ($1, $2, $3)
+`
+	queryGetHoldLots = `
+	SELECT lot_id, amount
+	FROM public.balance_hold_lots
+	WHERE hold_id = $1
+`
+
+	queryGetWithdrawal = `
+	SELECT order_number, user_login, type, amount, created_at, COALESCE(merchant_id, '')
+	FROM public.transactions
+	WHERE order_number = $1 AND type = 'WITHDRAWAL'
+`
+	queryGetRefundedAmount = `
+	SELECT COALESCE(SUM(amount), 0)
+	FROM public.transactions
+	WHERE order_number = $1 AND type = 'REFUND'
+`
+	queryGetWithdrawals = `
+	SELECT w.order_number, w.user_login, w.type, w.amount, w.created_at, COALESCE(r.amount, 0)
+	FROM public.transactions AS w
+	LEFT JOIN (
+		SELECT order_number, SUM(amount) AS amount
+		FROM public.transactions
+		WHERE user_login = $1 AND type = 'REFUND'
+		GROUP BY order_number
+	) AS r
+	ON r.order_number = w.order_number
+	WHERE w.user_login = $1 AND w.type = 'WITHDRAWAL'
+	ORDER BY w.created_at ASC
+`
+
+	queryGetAccrualTotals = `
+	SELECT u.login, COALESCE(SUM(CASE WHEN t.type = 'REVERSAL' THEN -t.amount ELSE t.amount END
+		/ COALESCE(NULLIF(o.accrual_multiplier, 0), 1)), 0)
+	FROM public.users AS u
+	LEFT JOIN public.transactions AS t
+	ON t.user_login = u.login AND t.type IN ('ACCRUAL', 'REVERSAL') AND t.created_at >= $1
+	LEFT JOIN public.orders AS o
+	ON o.id = t.order_number
+	GROUP BY u.login
+`
+	queryGetUserAccrualTotal = `
+	SELECT COALESCE(SUM(CASE WHEN t.type = 'REVERSAL' THEN -t.amount ELSE t.amount END
+		/ COALESCE(NULLIF(o.accrual_multiplier, 0), 1)), 0)
+	FROM public.transactions AS t
+	LEFT JOIN public.orders AS o
+	ON o.id = t.order_number
+	WHERE t.user_login = $1 AND t.type IN ('ACCRUAL', 'REVERSAL') AND t.created_at >= $2
+`
+	queryDeleteStaleUserTiers = `
+	DELETE FROM public.user_tiers
+	WHERE evaluated_at < $1
+`
+	querySaveUserTier = `
+	INSERT INTO public.user_tiers
+		(
+			user_login, tier, accrued, evaluated_at
+		)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_login) DO UPDATE
+	SET tier = EXCLUDED.tier, accrued = EXCLUDED.accrued, evaluated_at = EXCLUDED.evaluated_at
+`
+	queryGetUserTier = `
+	SELECT user_login, tier, accrued, evaluated_at
+	FROM public.user_tiers
+	WHERE user_login = $1
+`
+
+	queryGetUserByReferralCode = `
+	SELECT login
+	FROM public.users
+	WHERE referral_code = $1
+`
+	queryCheckSelfReferral = `
+	SELECT login = $1 OR (registration_ip IS NOT NULL AND registration_ip = $3)
+	FROM public.users
+	WHERE login = $2
+`
+	querySetUserReferral = `
+	UPDATE public.users
+	SET referral_code = $2, referred_by = NULLIF($3, ''), registration_ip = NULLIF($4, '')
+	WHERE login = $1
+`
+	querySetReferralCode = `
+	UPDATE public.users
+	SET referral_code = $2
+	WHERE login = $1 AND referral_code IS NULL
+`
+	queryGetReferralCode = `
+	SELECT COALESCE(referral_code, '')
+	FROM public.users
+	WHERE login = $1
+`
+	querySettleReferral = `
+	UPDATE public.users
+	SET referral_settled = true
+	WHERE login = $1 AND referred_by IS NOT NULL AND NOT referral_settled
+	RETURNING referred_by
+`
+	queryCountReferralRewards = `
+	SELECT COUNT(*)
+	FROM public.transactions
+	WHERE user_login = $1 AND type = 'REFERRAL'
+`
+	queryGetReferralStats = `
+	SELECT
+		(SELECT COUNT(*) FROM public.users WHERE referred_by = $1),
+		(SELECT COUNT(*) FROM public.transactions WHERE user_login = $1 AND type = 'REFERRAL'),
+		(SELECT COALESCE(SUM(amount), 0) FROM public.transactions WHERE user_login = $1 AND type = 'REFERRAL')
+`
+
+	queryCheckUserExists = `
+	SELECT EXISTS (SELECT 1 FROM public.users WHERE login = $1)
+`
+	queryGetTransferredAmount = `
+	SELECT COALESCE(SUM(amount), 0)
+	FROM public.transactions
+	WHERE user_login = $1 AND type = 'TRANSFER_OUT' AND created_at >= $2
+`
+	queryInsertTransfer = `
+	INSERT INTO public.transfers
+		(
+			sender_login, recipient_login, amount, note, status, created_at, expires_at
+		)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id
+`
+	queryGetTransfer = `
+	SELECT id, sender_login, recipient_login, amount, note, status, created_at, expires_at, completed_at
+	FROM public.transfers
+	WHERE id = $1
+`
+	queryGetUserTransfers = `
+	SELECT id, sender_login, recipient_login, amount, note, status, created_at, expires_at, completed_at
+	FROM public.transfers
+	WHERE sender_login = $1 OR (recipient_login = $1 AND status = 'COMPLETED')
+	ORDER BY created_at ASC
+`
+	queryUpdateTransferStatus = `
+	UPDATE public.transfers
+	SET status = $2, completed_at = $3
+	WHERE id = $1
+`
+	queryFailTransferConfirmation = `
+	UPDATE public.transfers
+	SET failed_attempts = failed_attempts + 1,
+		status = CASE WHEN failed_attempts + 1 >= $2 THEN 'CANCELLED' ELSE status END
+	WHERE id = $1 AND status = 'PENDING'
+	RETURNING failed_attempts, status
+`
+
+	campaignColumns = `id, name, starts_at, ends_at, multiplier, bonus, weekdays, time_from, time_to, tiers,
+	registered_after, registered_before, first_order_only, active, created_at`
+	queryInsertCampaign = `
+	INSERT INTO public.campaigns
+		(
+			name, starts_at, ends_at, multiplier, bonus, weekdays, time_from, time_to, tiers,
+			registered_after, registered_before, first_order_only, active, created_at
+		)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	RETURNING id
+`
+	queryUpdateCampaign = `
+	UPDATE public.campaigns
+	SET name = $2, starts_at = $3, ends_at = $4, multiplier = $5, bonus = $6, weekdays = $7, time_from = $8, time_to = $9,
+		tiers = $10, registered_after = $11, registered_before = $12, first_order_only = $13, active = $14
+	WHERE id = $1 AND deleted_at IS NULL
+`
+	queryDeleteCampaign = `
+	UPDATE public.campaigns
+	SET active = false, deleted_at = $2
+	WHERE id = $1 AND deleted_at IS NULL
+`
+	queryGetCampaigns = `
+	SELECT ` + campaignColumns + `
+	FROM public.campaigns
+	WHERE deleted_at IS NULL
+	ORDER BY starts_at ASC, id ASC
+`
+	queryGetCampaign = `
+	SELECT ` + campaignColumns + `
+	FROM public.campaigns
+	WHERE id = $1 AND deleted_at IS NULL
+`
+	queryGetActiveCampaigns = `
+	SELECT ` + campaignColumns + `
+	FROM public.campaigns
+	WHERE active AND deleted_at IS NULL AND starts_at <= $1 AND ends_at > $1
+	ORDER BY id ASC
+`
+	queryGetUserRegisteredAt = `
+	SELECT registered_at
+	FROM public.users
+	WHERE login = $1
+`
+	queryCountProcessedOrders = `
+	SELECT COUNT(*)
+	FROM public.orders
+	WHERE user_login = $1 AND status = 'PROCESSED' AND id <> $2
+`
+	queryGetOrderBonuses = `
+	SELECT b.user_login, b.type, COALESCE(b.campaign_id, 0), b.amount
+	FROM public.transactions AS b
+	WHERE b.order_number = $1 AND b.type IN ('CAMPAIGN', 'REFERRAL')
+		AND NOT EXISTS (
+			SELECT 1 FROM public.transactions AS r
+			WHERE r.order_number = b.order_number AND r.user_login = b.user_login AND r.type = 'BONUS_REVERSAL'
+				AND COALESCE(r.campaign_id, 0) = COALESCE(b.campaign_id, 0)
+		)
+	ORDER BY b.id ASC
+`
+	queryCheckCampaignBonusExists = `
+	SELECT EXISTS (
+		SELECT 1 FROM public.transactions
+		WHERE order_number = $1 AND campaign_id = $2 AND type = 'CAMPAIGN'
+	)
+`
+
+	queryGetSpentAmount = `
+	SELECT (
+		SELECT COALESCE(SUM(amount), 0)
+		FROM public.transactions
+		WHERE user_login = $1 AND type IN ('WITHDRAWAL', 'REDEMPTION') AND created_at >= $2
+	) + (
+		SELECT COALESCE(SUM(amount), 0)
+		FROM public.balance_holds
+		WHERE user_login = $1 AND status = 'ACTIVE' AND created_at >= $2
+	)
+`
+
+	rewardItemColumns     = `id, name, description, price, stock, valid_from, valid_until, active, created_at`
+	queryInsertRewardItem = `
+	INSERT INTO public.reward_items
+		(
+			name, description, price, stock, valid_from, valid_until, active, created_at
+		)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id
+`
+	queryUpdateRewardItem = `
+	UPDATE public.reward_items
+	SET name = $2, description = $3, price = $4, stock = $5, valid_from = $6, valid_until = $7, active = $8
+	WHERE id = $1 AND deleted_at IS NULL
+`
+	queryDeleteRewardItem = `
+	UPDATE public.reward_items
+	SET active = false, deleted_at = $2
+	WHERE id = $1 AND deleted_at IS NULL
+`
+	queryGetRewardItems = `
+	SELECT ` + rewardItemColumns + `
+	FROM public.reward_items
+	WHERE deleted_at IS NULL
+	ORDER BY id ASC
+`
+	queryGetAvailableRewardItems = `
+	SELECT ` + rewardItemColumns + `
+	FROM public.reward_items
+	WHERE deleted_at IS NULL AND active
+		AND (valid_from IS NULL OR valid_from <= $1)
+		AND (valid_until IS NULL OR valid_until > $1)
+		AND (stock IS NULL OR stock > 0)
+	ORDER BY price ASC, id ASC
+`
+	queryGetRewardItem = `
+	SELECT ` + rewardItemColumns + `
+	FROM public.reward_items
+	WHERE id = $1 AND deleted_at IS NULL
+`
+	queryReserveRewardStock = `
+	UPDATE public.reward_items
+	SET stock = stock - $2
+	WHERE id = $1 AND stock IS NOT NULL AND stock >= $2
+`
+	queryRestoreRewardStock = `
+	UPDATE public.reward_items
+	SET stock = stock + $2
+	WHERE id = $1 AND stock IS NOT NULL
+`
+	redemptionColumns     = `r.id, r.user_login, r.item_id, i.name, r.quantity, r.amount, r.status, r.created_at, r.updated_at`
+	queryInsertRedemption = `
+	INSERT INTO public.redemptions
+		(
+			user_login, item_id, quantity, amount, status, created_at, updated_at
+		)
+	VALUES ($1, $2, $3, $4, $5, $6, $6)
+	RETURNING id
+`
+	queryGetUserRedemptions = `
+	SELECT ` + redemptionColumns + `
+	FROM public.redemptions AS r
+	JOIN public.reward_items AS i
+	ON i.id = r.item_id
+	WHERE r.user_login = $1
+	ORDER BY r.created_at ASC
+`
+	queryGetRedemption = `
+	SELECT ` + redemptionColumns + `
+	FROM public.redemptions AS r
+	JOIN public.reward_items AS i
+	ON i.id = r.item_id
+	WHERE r.id = $1
+`
+	queryUpdateRedemptionStatus = `
+	UPDATE public.redemptions
+	SET status = $2, updated_at = $3
+	WHERE id = $1
+`
+	queryInsertVoucherBatch = `
+	INSERT INTO public.voucher_batches
+		(
+			name, value, quantity, expires_at, created_by, created_at
+		)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id
+`
+	queryGetVoucherBatches = `
+	SELECT b.id, b.name, b.value, b.quantity, b.expires_at, b.created_by, b.created_at, COUNT(v.redeemed_by)
+	FROM public.voucher_batches AS b
+	LEFT JOIN public.vouchers AS v
+	ON v.batch_id = b.id
+	GROUP BY b.id
+	ORDER BY b.created_at ASC
+`
+	queryRedeemVoucher = `
+	UPDATE public.vouchers
+	SET redeemed_by = $2, redeemed_at = $3
+	WHERE code = $1 AND redeemed_by IS NULL AND (expires_at IS NULL OR expires_at > $3)
+	RETURNING batch_id, value
+`
+	queryGetVoucher = `
+	SELECT batch_id, value, expires_at, COALESCE(redeemed_by, '')
+	FROM public.vouchers
+	WHERE code = $1
+`
+	queryInsertVoucherAttempt = `
+	INSERT INTO public.voucher_attempts
+		(
+			user_login, ip, success, created_at
+		)
+	VALUES ($1, $2, $3, $4)
+`
+	queryCountFailedVoucherAttempts = `
+	SELECT
+		COUNT(*) FILTER (WHERE user_login = $1),
+		COUNT(*) FILTER (WHERE $2 <> '' AND ip = $2)
+	FROM public.voucher_attempts
+	WHERE NOT success AND created_at >= $3 AND (user_login = $1 OR ($2 <> '' AND ip = $2))
+`
+	queryReserveIdempotencyKey = `
+	INSERT INTO public.idempotency_keys
+		(
+			user_login, key, request_hash, created_at, locked_until, owner_token
+		)
+	VALUES ($1, $2, $3, $4, $6, $7)
+	ON CONFLICT (user_login, key) DO UPDATE
+	SET request_hash = EXCLUDED.request_hash, status_code = 0, content_type = '', body = NULL,
+		created_at = EXCLUDED.created_at, locked_until = EXCLUDED.locked_until, owner_token = EXCLUDED.owner_token
+	WHERE public.idempotency_keys.created_at < $5
+		OR (public.idempotency_keys.status_code = 0
+			AND public.idempotency_keys.request_hash = EXCLUDED.request_hash
+			AND COALESCE(public.idempotency_keys.locked_until, public.idempotency_keys.created_at) < $4)
+	RETURNING user_login
+`
+	queryGetIdempotencyKey = `
+	SELECT user_login, key, request_hash, status_code, content_type, COALESCE(body, ''::bytea), created_at, locked_until
+	FROM public.idempotency_keys
+	WHERE user_login = $1 AND key = $2
+`
+	queryCompleteIdempotencyKey = `
+	UPDATE public.idempotency_keys
+	SET status_code = $3, content_type = $4, body = $5, locked_until = NULL
+	WHERE user_login = $1 AND key = $2 AND owner_token = $6 AND status_code = 0
+`
+	queryRenewIdempotencyKey = `
+	UPDATE public.idempotency_keys
+	SET locked_until = $4
+	WHERE user_login = $1 AND key = $2 AND owner_token = $3 AND status_code = 0
+`
+	queryDeleteIdempotencyKey = `
+	DELETE FROM public.idempotency_keys
+	WHERE user_login = $1 AND key = $2 AND owner_token = $3 AND status_code = 0
+`
+	queryDeleteExpiredIdempotencyKeys = `
+	DELETE FROM public.idempotency_keys
+	WHERE created_at < $1
+`
+	queryInsertEvent = `
+	INSERT INTO public.events
+		(
+			user_login, type, payload, created_at
+		)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id
+`
+	queryGetEventsAfter = `
+	SELECT id, user_login, type, payload, created_at
+	FROM public.events
+	WHERE user_login = $1 AND id > $2
+	ORDER BY id ASC
+	LIMIT $3
+`
+	queryDeleteExpiredEvents = `
+	DELETE FROM public.events
+	WHERE created_at < $1
+`
+	queryInsertWebhook = `
+	INSERT INTO public.webhooks
+		(
+			user_login, url, secret, event_types, active, created_at, updated_at
+		)
+	VALUES ($1, $2, $3, $4, $5, $6, $6)
+	RETURNING id
+`
+	queryGetWebhooks = `
+	SELECT id, user_login, url, secret, event_types, active, failure_count, disabled_at, created_at
+	FROM public.webhooks
+	WHERE user_login = $1
+	ORDER BY id ASC
+`
+	queryGetWebhook = `
+	SELECT id, user_login, url, secret, event_types, active, failure_count, disabled_at, created_at
+	FROM public.webhooks
+	WHERE id = $1 AND user_login = $2
+`
+	queryUpdateWebhook = `
+	UPDATE public.webhooks
+	SET url = $3, event_types = $4, active = $5,
+		failure_count = CASE WHEN $5 AND NOT active THEN 0 ELSE failure_count END,
+		disabled_at = CASE WHEN $5 THEN NULL ELSE disabled_at END,
+		updated_at = $6
+	WHERE id = $1 AND user_login = $2
+`
+	queryDeleteWebhook = `
+	DELETE FROM public.webhooks
+	WHERE id = $1 AND user_login = $2
+`
+	queryInsertWebhookDeliveries = `
+	INSERT INTO public.webhook_deliveries
+		(
+			webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at
+		)
+	SELECT id, $2, $3, $4, 'PENDING', $5, $5
+	FROM public.webhooks
+	WHERE user_login = $1 AND active AND $3 = ANY(event_types)
+`
+	queryGetDueWebhookDeliveries = `
+	SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.response_code, d.error,
+		d.next_attempt_at, d.created_at, d.delivered_at, w.url, w.secret
+	FROM public.webhook_deliveries AS d
+	INNER JOIN public.webhooks AS w
+	ON w.id = d.webhook_id
+	WHERE d.status = 'PENDING' AND d.next_attempt_at <= $1 AND w.active
+	ORDER BY d.next_attempt_at ASC, d.id ASC
+	LIMIT $2
+`
+	queryUpdateWebhookDelivery = `
+	UPDATE public.webhook_deliveries
+	SET status = $2, attempts = $3, response_code = $4, error = $5, next_attempt_at = $6, delivered_at = $7
+	WHERE id = $1
+`
+	queryResetWebhookFailures = `
+	UPDATE public.webhooks
+	SET failure_count = 0
+	WHERE id = $1
+	RETURNING active
+`
+	queryAddWebhookFailure = `
+	UPDATE public.webhooks
+	SET failure_count = failure_count + 1,
+		active = CASE WHEN failure_count + 1 >= $2 THEN false ELSE active END,
+		disabled_at = CASE WHEN failure_count + 1 >= $2 AND active THEN $3 ELSE disabled_at END
+	WHERE id = $1
+	RETURNING active
+`
+	queryGetWebhookDeliveries = `
+	SELECT id, webhook_id, event_id, event_type, payload, status, attempts, response_code, error,
+		next_attempt_at, created_at, delivered_at
+	FROM public.webhook_deliveries
+	WHERE webhook_id = $1
+	ORDER BY id DESC
+	LIMIT $2
 `
 )
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (s *databaseStorage) GetUserByReferralCode(code string) (string, error) {
+	ctx := context.Background()
+	var user string
+
+	err := s.conn.QueryRow(ctx, queryGetUserByReferralCode, code).Scan(&user)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return "", NewDBReferralError(code, true, errors.New("реферальный код "+code+" не найден"))
+	}
+
+	if err != nil {
+		log.Println("Ошибка при поиске пользователя по реферальному коду "+code+":", err)
+		return "", err
+	}
+
+	return user, nil
+}
+
+func (s *databaseStorage) IsSelfReferral(user, referrer, ip string) (bool, error) {
+	ctx := context.Background()
+	var self bool
+
+	err := s.conn.QueryRow(ctx, queryCheckSelfReferral, user, referrer, ip).Scan(&self)
+	if err != nil {
+		log.Println("Ошибка при проверке приглашения пользователя "+user+" пользователем "+referrer+":", err)
+		return false, err
+	}
+
+	return self, nil
+}
+
+func (s *databaseStorage) SetUserReferral(user, code, referrer, ip string) error {
+	log.Printf("Сохранение реферальных данных пользователя '%v': код '%v', пригласивший '%v', IP '%v'\n", user, code, referrer, ip)
+
+	ctx := context.Background()
+	var pgErr *pgconn.PgError
+
+	_, err := s.conn.Exec(ctx, querySetUserReferral, user, code, referrer, ip)
+	if err != nil && errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		return NewDBReferralError(code, false, err)
+	}
+
+	if err != nil {
+		log.Println("Ошибка при сохранении реферальных данных пользователя:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) SetReferralCode(user, code string) error {
+	ctx := context.Background()
+	var pgErr *pgconn.PgError
+
+	_, err := s.conn.Exec(ctx, querySetReferralCode, user, code)
+	if err != nil && errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		return NewDBReferralError(code, false, err)
+	}
+
+	if err != nil {
+		log.Println("Ошибка при сохранении реферального кода пользователя:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) GetReferralStats(user string) (*ReferralStats, error) {
+	ctx := context.Background()
+	var stats ReferralStats
+
+	err := s.conn.QueryRow(ctx, queryGetReferralCode, user).Scan(&stats.Code)
+	if err != nil {
+		log.Println("Ошибка при считывании реферального кода пользователя "+user+":", err)
+		return nil, err
+	}
+
+	err = s.conn.QueryRow(ctx, queryGetReferralStats, user).Scan(&stats.Invited, &stats.Rewarded, &stats.Earned)
+	if err != nil {
+		log.Println("Ошибка при считывании статистики приглашений пользователя "+user+":", err)
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func (s *databaseStorage) creditBonus(transaction *Transaction, withHold bool) error {
+	account, err := s.GetUserAccount(transaction.UserLogin)
+	if err != nil {
+		return err
+	}
+
+	createdAt := transaction.CreatedAt.Time
+
+	if withHold {
+		err = s.creditWithHold(account, transaction.OrderNumber, transaction.Amount, createdAt)
+	} else if credited := account.credit(transaction.Amount); credited > 0 {
+		err = s.addLot(transaction.UserLogin, transaction.OrderNumber, credited, createdAt, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.UpdateUserAccount(account)
+	if err != nil {
+		return err
+	}
+
+	return s.AddTransaction(transaction)
+}
+
+func (s *databaseStorage) AddWelcomeBonus(user string, amount float32) error {
+	log.Printf("Начисление приветственного бонуса пользователю '%v' на сумму '%v'\n", user, amount)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		return tx.creditBonus(&Transaction{
+			UserLogin: user,
+			Type:      TransactionTypeWelcome,
+			Amount:    amount,
+			CreatedAt: CustomDateTime{Time: time.Now()},
+		}, false)
+	})
+}
+
+func (s *databaseStorage) RewardReferral(referee, orderNumber string, amount float32, limit int) (string, error) {
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	var referrer string
+	err := s.inTransaction(func(tx *databaseStorage) error {
+		var err error
+		referrer, err = tx.rewardReferral(referee, orderNumber, amount, limit)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return referrer, nil
+}
+
+func (s *databaseStorage) rewardReferral(referee, orderNumber string, amount float32, limit int) (string, error) {
+	ctx := context.Background()
+	var referrer string
+
+	err := s.conn.QueryRow(ctx, querySettleReferral, referee).Scan(&referrer)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+
+	if err != nil {
+		log.Println("Ошибка при проверке приглашения пользователя "+referee+":", err)
+		return "", err
+	}
+
+	var rewarded int
+	err = s.conn.QueryRow(ctx, queryCountReferralRewards, referrer).Scan(&rewarded)
+	if err != nil {
+		log.Println("Ошибка при подсчёте вознаграждений за приглашения пользователя "+referrer+":", err)
+		return "", err
+	}
+
+	if limit > 0 && rewarded >= limit {
+		log.Printf("Пользователь '%v' достиг лимита вознаграждений за приглашения (%v), бонус за '%v' не начислен\n", referrer, limit, referee)
+		return "", nil
+	}
+
+	log.Printf("Начисление бонуса пользователю '%v' за приглашение '%v' по заказу '%v' на сумму '%v'\n", referrer, referee, orderNumber, amount)
+
+	err = s.creditBonus(&Transaction{
+		OrderNumber: orderNumber,
+		UserLogin:   referrer,
+		Type:        TransactionTypeReferral,
+		Amount:      amount,
+		CreatedAt:   CustomDateTime{Time: time.Now()},
+	}, true)
+	if err != nil {
+		return "", err
+	}
+
+	return referrer, nil
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *databaseStorage) GetWithdrawals(user string) ([]Withdrawal, error) {
+	log.Printf("Получение списаний пользователя '%v'\n", user)
+
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetWithdrawals, user)
+	if err != nil {
+		log.Println("Ошибка при запросе списаний пользователя:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]Withdrawal, 0)
+
+	for rows.Next() {
+		var withdrawal Withdrawal
+		err = rows.Scan(&withdrawal.OrderNumber, &withdrawal.UserLogin, &withdrawal.Type, &withdrawal.Amount, &withdrawal.CreatedAt.Time, &withdrawal.Refunded)
+		if err != nil {
+			log.Println("Ошибка при считывании записи списания из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, withdrawal)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании записей списаний из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type WithdrawalLimits struct {
+	Daily   float32
+	Monthly float32
+}
+
+func (s *databaseStorage) getSpentAmount(user string, since time.Time) (float32, error) {
+	ctx := context.Background()
+	var amount float32
+
+	err := s.conn.QueryRow(ctx, queryGetSpentAmount, user, since).Scan(&amount)
+	if err != nil {
+		log.Println("Ошибка при подсчёте списаний пользователя "+user+":", err)
+		return 0, err
+	}
+
+	return amount, nil
+}
+
+func (s *databaseStorage) checkWithdrawalLimits(user string, amount float32, limits WithdrawalLimits) error {
+	now := time.Now()
+
+	if limits.Daily > 0 {
+		dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+		spent, err := s.getSpentAmount(user, dayStart)
+		if err != nil {
+			return err
+		}
+
+		if spent+amount > limits.Daily {
+			return NewDBWithdrawalLimitError(user, true, false, limits.Daily, spent,
+				errors.New("превышен дневной лимит списаний ("+strconv.FormatFloat(float64(limits.Daily), 'f', -1, 32)+"), сегодня уже списано или зарезервировано "+strconv.FormatFloat(float64(spent), 'f', -1, 32)+" баллов"))
+		}
+	}
+
+	if limits.Monthly > 0 {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+		spent, err := s.getSpentAmount(user, monthStart)
+		if err != nil {
+			return err
+		}
+
+		if spent+amount > limits.Monthly {
+			return NewDBWithdrawalLimitError(user, false, true, limits.Monthly, spent,
+				errors.New("превышен месячный лимит списаний ("+strconv.FormatFloat(float64(limits.Monthly), 'f', -1, 32)+"), в этом месяце уже списано или зарезервировано "+strconv.FormatFloat(float64(spent), 'f', -1, 32)+" баллов"))
+		}
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) Refund(orderNumber, merchant string, amount float32) (*Transaction, error) {
+	log.Printf("Возврат баллов по заказу '%v', магазин '%v', сумма '%v'\n", orderNumber, merchant, amount)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	var refund *Transaction
+	err := s.inTransaction(func(tx *databaseStorage) error {
+		var err error
+		refund, err = tx.refund(orderNumber, merchant, amount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Возвращено '%v' баллов по заказу '%v' пользователю '%v'\n", refund.Amount, orderNumber, refund.UserLogin)
+	return refund, nil
+}
+
+func (s *databaseStorage) refund(orderNumber, merchant string, amount float32) (*Transaction, error) {
+	ctx := context.Background()
+	var withdrawal Transaction
+
+	row := s.conn.QueryRow(ctx, queryGetWithdrawal, orderNumber)
+	err := row.Scan(&withdrawal.OrderNumber, &withdrawal.UserLogin, &withdrawal.Type, &withdrawal.Amount, &withdrawal.CreatedAt.Time, &withdrawal.MerchantID)
+
+	if err != nil && err == pgx.ErrNoRows {
+		return nil, NewDBRefundError(orderNumber, true, false, errors.New("списание баллов по заказу "+orderNumber+" не найдено"))
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании списания по заказу "+orderNumber+" из БД:", err)
+		return nil, err
+	}
+
+	if merchant != "" && withdrawal.MerchantID != merchant {
+		return nil, NewDBRefundError(orderNumber, true, false, errors.New("списание баллов по заказу "+orderNumber+" не найдено у магазина "+merchant))
+	}
+
+	var refunded float32
+	err = s.conn.QueryRow(ctx, queryGetRefundedAmount, orderNumber).Scan(&refunded)
+	if err != nil {
+		log.Println("Ошибка при считывании суммы возвратов по заказу "+orderNumber+" из БД:", err)
+		return nil, err
+	}
+
+	refundable := withdrawal.Amount - refunded
+	if amount == 0 {
+		amount = refundable
+	}
+
+	if amount < amountPrecision || amount > refundable+amountPrecision {
+		return nil, NewDBRefundError(orderNumber, false, true, errors.New("сумма возврата "+strconv.FormatFloat(float64(amount), 'E', -1, 32)+" превышает доступную к возврату по заказу "+orderNumber+" ("+strconv.FormatFloat(float64(refundable), 'E', -1, 32)+")"))
+	}
+
+	account, err := s.GetUserAccount(withdrawal.UserLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := Transaction{
+		OrderNumber: orderNumber,
+		UserLogin:   withdrawal.UserLogin,
+		Type:        TransactionTypeRefund,
+		Amount:      amount,
+		CreatedAt:   CustomDateTime{Time: time.Now()},
+		MerchantID:  withdrawal.MerchantID,
+	}
+
+	err = s.AddTransaction(&refund)
+	if err != nil {
+		return nil, err
+	}
+
+	credited := account.credit(amount)
+	if credited > 0 {
+		err = s.addLot(account.UserLogin, orderNumber, credited, refund.CreatedAt.Time, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	account.Withdrawn -= amount
+
+	err = s.UpdateUserAccount(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return &refund, nil
+}
@@ -0,0 +1,403 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	RedemptionStatusPending   = "PENDING"
+	RedemptionStatusFulfilled = "FULFILLED"
+	RedemptionStatusCancelled = "CANCELLED"
+
+	redemptionReferencePrefix = "REDEMPTION-"
+)
+
+type RewardItem struct {
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Price       float32         `json:"price"`
+	Stock       *int32          `json:"stock,omitempty"`
+	ValidFrom   *CustomDateTime `json:"valid_from,omitempty"`
+	ValidUntil  *CustomDateTime `json:"valid_until,omitempty"`
+	Active      bool            `json:"active"`
+	CreatedAt   CustomDateTime  `json:"created_at"`
+}
+
+type Redemption struct {
+	ID          int64          `json:"id"`
+	UserLogin   string         `json:"-"`
+	ItemID      int64          `json:"item_id"`
+	ItemName    string         `json:"item"`
+	Quantity    int32          `json:"quantity"`
+	Amount      float32        `json:"sum"`
+	Status      string         `json:"status"`
+	StatusLabel string         `json:"status_label,omitempty"`
+	CreatedAt   CustomDateTime `json:"created_at"`
+	UpdatedAt   CustomDateTime `json:"updated_at"`
+}
+
+func (i *RewardItem) isAvailable(at time.Time) bool {
+	if !i.Active {
+		return false
+	}
+
+	if i.ValidFrom != nil && at.Before(i.ValidFrom.Time) {
+		return false
+	}
+
+	if i.ValidUntil != nil && !at.Before(i.ValidUntil.Time) {
+		return false
+	}
+
+	return true
+}
+
+func (r *Redemption) reference() string {
+	return redemptionReferencePrefix + strconv.FormatInt(r.ID, 10)
+}
+
+func (s *databaseStorage) CreateRewardItem(item *RewardItem) error {
+	log.Printf("Добавление в каталог вознаграждений '%v' стоимостью '%v'\n", item.Name, item.Price)
+
+	ctx := context.Background()
+
+	err := s.conn.QueryRow(ctx, queryInsertRewardItem, item.Name, item.Description, item.Price, item.Stock,
+		optionalTime(item.ValidFrom), optionalTime(item.ValidUntil), item.Active, item.CreatedAt.Time).Scan(&item.ID)
+	if err != nil {
+		log.Println("Ошибка при добавлении вознаграждения в БД:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) UpdateRewardItem(item *RewardItem) error {
+	log.Printf("Обновление вознаграждения '%v' в каталоге\n", item.ID)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryUpdateRewardItem, item.ID, item.Name, item.Description, item.Price, item.Stock,
+		optionalTime(item.ValidFrom), optionalTime(item.ValidUntil), item.Active)
+	if err != nil {
+		log.Println("Ошибка при обновлении вознаграждения в БД:", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return NewDBRewardError(item.ID, 0, true, false, false, false, errors.New("вознаграждение "+strconv.FormatInt(item.ID, 10)+" не найдено"))
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) DeleteRewardItem(itemID int64) error {
+	log.Printf("Удаление вознаграждения '%v' из каталога\n", itemID)
+
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryDeleteRewardItem, itemID, time.Now())
+	if err != nil {
+		log.Println("Ошибка при удалении вознаграждения из БД:", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return NewDBRewardError(itemID, 0, true, false, false, false, errors.New("вознаграждение "+strconv.FormatInt(itemID, 10)+" не найдено"))
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) GetRewardItems(availableOnly bool) ([]RewardItem, error) {
+	ctx := context.Background()
+
+	var rows pgx.Rows
+	var err error
+
+	if availableOnly {
+		rows, err = s.conn.Query(ctx, queryGetAvailableRewardItems, time.Now())
+	} else {
+		rows, err = s.conn.Query(ctx, queryGetRewardItems)
+	}
+	if err != nil {
+		log.Println("Ошибка при запросе каталога вознаграждений:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]RewardItem, 0)
+
+	for rows.Next() {
+		item, err := scanRewardItem(rows)
+		if err != nil {
+			log.Println("Ошибка при считывании вознаграждения из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, *item)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании каталога вознаграждений:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) GetRewardItem(itemID int64) (*RewardItem, error) {
+	ctx := context.Background()
+
+	item, err := scanRewardItem(s.conn.QueryRow(ctx, queryGetRewardItem, itemID))
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании вознаграждения из БД:", err)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func scanRewardItem(row pgx.Row) (*RewardItem, error) {
+	var item RewardItem
+	var validFrom, validUntil *time.Time
+
+	err := row.Scan(&item.ID, &item.Name, &item.Description, &item.Price, &item.Stock, &validFrom, &validUntil,
+		&item.Active, &item.CreatedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	if validFrom != nil {
+		item.ValidFrom = &CustomDateTime{Time: *validFrom}
+	}
+
+	if validUntil != nil {
+		item.ValidUntil = &CustomDateTime{Time: *validUntil}
+	}
+
+	return &item, nil
+}
+
+func (s *databaseStorage) Redeem(redemption *Redemption) error {
+	log.Printf("Обмен баллов пользователя '%v' на вознаграждение '%v', количество '%v'\n", redemption.UserLogin, redemption.ItemID, redemption.Quantity)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	item, err := s.GetRewardItem(redemption.ItemID)
+	if err != nil {
+		return err
+	}
+
+	if item == nil {
+		return NewDBRewardError(redemption.ItemID, 0, true, false, false, false, errors.New("вознаграждение "+strconv.FormatInt(redemption.ItemID, 10)+" не найдено"))
+	}
+
+	now := time.Now()
+
+	if !item.isAvailable(now) {
+		return NewDBRewardError(item.ID, 0, false, true, false, false, errors.New("вознаграждение "+item.Name+" сейчас недоступно"))
+	}
+
+	if item.Stock != nil && *item.Stock < redemption.Quantity {
+		return NewDBRewardError(item.ID, 0, false, false, true, false, errors.New("вознаграждение "+item.Name+" закончилось, в наличии "+strconv.Itoa(int(*item.Stock))))
+	}
+
+	redemption.ItemName = item.Name
+	redemption.Amount = item.Price * float32(redemption.Quantity)
+	redemption.Status = RedemptionStatusPending
+	redemption.CreatedAt = CustomDateTime{Time: now}
+	redemption.UpdatedAt = CustomDateTime{Time: now}
+
+	err = s.refreshUserLots(redemption.UserLogin)
+	if err != nil {
+		return err
+	}
+
+	account, err := s.GetUserAccount(redemption.UserLogin)
+	if err != nil {
+		return err
+	}
+
+	if redemption.Amount > account.Balance {
+		return NewDBAccountError(redemption.UserLogin, true, errors.New("на счёте пользователя "+redemption.UserLogin+" недостаточно средств ("+strconv.FormatFloat(float64(account.Balance), 'E', -1, 32)+") для обмена на "+strconv.FormatFloat(float64(redemption.Amount), 'E', -1, 32)+" баллов"))
+	}
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		ctx := context.Background()
+
+		if item.Stock != nil {
+			ct, err := tx.conn.Exec(ctx, queryReserveRewardStock, item.ID, redemption.Quantity)
+			if err != nil {
+				log.Println("Ошибка при резервировании вознаграждения:", err)
+				return err
+			}
+
+			if ct.RowsAffected() == 0 {
+				return NewDBRewardError(item.ID, 0, false, false, true, false, errors.New("вознаграждение "+item.Name+" закончилось"))
+			}
+		}
+
+		err := tx.conn.QueryRow(ctx, queryInsertRedemption, redemption.UserLogin, redemption.ItemID, redemption.Quantity,
+			redemption.Amount, redemption.Status, now).Scan(&redemption.ID)
+		if err != nil {
+			log.Println("Ошибка при добавлении обмена баллов в БД:", err)
+			return err
+		}
+
+		_, err = tx.consumeLots(redemption.UserLogin, "", redemption.Amount)
+		if err != nil {
+			return err
+		}
+
+		account.Balance -= redemption.Amount
+		account.Withdrawn += redemption.Amount
+
+		err = tx.UpdateUserAccount(account)
+		if err != nil {
+			return err
+		}
+
+		return tx.AddTransaction(&Transaction{
+			OrderNumber: redemption.reference(),
+			UserLogin:   redemption.UserLogin,
+			Type:        TransactionTypeRedemption,
+			Amount:      redemption.Amount,
+			CreatedAt:   CustomDateTime{Time: now},
+		})
+	})
+}
+
+func (s *databaseStorage) GetRedemptions(user string) ([]Redemption, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetUserRedemptions, user)
+	if err != nil {
+		log.Println("Ошибка при запросе обменов баллов пользователя:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]Redemption, 0)
+
+	for rows.Next() {
+		redemption, err := scanRedemption(rows)
+		if err != nil {
+			log.Println("Ошибка при считывании обмена баллов из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, *redemption)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании обменов баллов из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanRedemption(row pgx.Row) (*Redemption, error) {
+	var redemption Redemption
+
+	err := row.Scan(&redemption.ID, &redemption.UserLogin, &redemption.ItemID, &redemption.ItemName, &redemption.Quantity,
+		&redemption.Amount, &redemption.Status, &redemption.CreatedAt.Time, &redemption.UpdatedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redemption, nil
+}
+
+func (s *databaseStorage) UpdateRedemptionStatus(redemptionID int64, status string) (*Redemption, error) {
+	log.Printf("Изменение статуса обмена баллов '%v' на '%v'\n", redemptionID, status)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	ctx := context.Background()
+
+	redemption, err := scanRedemption(s.conn.QueryRow(ctx, queryGetRedemption, redemptionID))
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewDBRewardError(0, redemptionID, true, false, false, false, errors.New("обмен баллов "+strconv.FormatInt(redemptionID, 10)+" не найден"))
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании обмена баллов из БД:", err)
+		return nil, err
+	}
+
+	if redemption.Status != RedemptionStatusPending {
+		return nil, NewDBRewardError(redemption.ItemID, redemptionID, false, false, false, true, errors.New("обмен баллов "+strconv.FormatInt(redemptionID, 10)+" уже находится в статусе "+redemption.Status))
+	}
+
+	now := time.Now()
+
+	err = s.inTransaction(func(tx *databaseStorage) error {
+		_, err := tx.conn.Exec(ctx, queryUpdateRedemptionStatus, redemptionID, status, now)
+		if err != nil {
+			log.Println("Ошибка при обновлении статуса обмена баллов:", err)
+			return err
+		}
+
+		if status != RedemptionStatusCancelled {
+			return nil
+		}
+
+		_, err = tx.conn.Exec(ctx, queryRestoreRewardStock, redemption.ItemID, redemption.Quantity)
+		if err != nil {
+			log.Println("Ошибка при возврате вознаграждения в каталог:", err)
+			return err
+		}
+
+		account, err := tx.GetUserAccount(redemption.UserLogin)
+		if err != nil {
+			return err
+		}
+
+		account.Withdrawn -= redemption.Amount
+		if account.Withdrawn < amountPrecision {
+			account.Withdrawn = 0
+		}
+
+		err = tx.UpdateUserAccount(account)
+		if err != nil {
+			return err
+		}
+
+		return tx.creditBonus(&Transaction{
+			OrderNumber: redemption.reference(),
+			UserLogin:   redemption.UserLogin,
+			Type:        TransactionTypeRefund,
+			Amount:      redemption.Amount,
+			CreatedAt:   CustomDateTime{Time: now},
+		}, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	redemption.Status = status
+	redemption.UpdatedAt = CustomDateTime{Time: now}
+
+	return redemption, nil
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+)
+
+type StatementEntry struct {
+	ID          int64          `json:"id"`
+	Type        string         `json:"type"`
+	OrderNumber string         `json:"order"`
+	Amount      float32        `json:"sum"`
+	LedgerTotal float32        `json:"ledger_total"`
+	CreatedAt   CustomDateTime `json:"processed_at"`
+	CampaignID  int64          `json:"campaign_id,omitempty"`
+}
+
+type StatementCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+type StatementFilter struct {
+	Types []string
+	From  *time.Time
+	To    *time.Time
+	Limit int
+	After *StatementCursor
+}
+
+type StatementPage struct {
+	Entries []StatementEntry
+	Next    *StatementCursor
+}
+
+var TransactionTypes = []string{
+	TransactionTypeAccrual,
+	TransactionTypeWithdrawal,
+	TransactionTypeReversal,
+	TransactionTypeExpiration,
+	TransactionTypeRefund,
+	TransactionTypeWelcome,
+	TransactionTypeReferral,
+	TransactionTypeTransferIn,
+	TransactionTypeTransferOut,
+	TransactionTypeCampaign,
+	TransactionTypeRedemption,
+	TransactionTypeVoucher,
+	TransactionTypeBonusReversal,
+}
+
+func (c *StatementCursor) String() string {
+	return encodeCursor(c.CreatedAt, strconv.FormatInt(c.ID, 10))
+}
+
+func ParseStatementCursor(cursor string) (*StatementCursor, error) {
+	createdAt, key, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return nil, errors.New("курсор страницы передан в неправильном формате")
+	}
+
+	return &StatementCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+func (s *databaseStorage) GetStatement(user string, filter *StatementFilter) (*StatementPage, error) {
+	ctx := context.Background()
+
+	var types []string
+	if len(filter.Types) > 0 {
+		types = filter.Types
+	}
+
+	var afterCreatedAt *time.Time
+	var afterID int64
+	if filter.After != nil {
+		afterCreatedAt = &filter.After.CreatedAt
+		afterID = filter.After.ID
+	}
+
+	var limit *int
+	if filter.Limit > 0 {
+		l := filter.Limit + 1
+		limit = &l
+	}
+
+	rows, err := s.conn.Query(ctx, queryGetStatement, user, types, filter.From, filter.To, afterCreatedAt, afterID, limit)
+	if err != nil {
+		log.Println("Ошибка при запросе выписки по счёту пользователя "+user+":", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]StatementEntry, 0)
+
+	for rows.Next() {
+		var entry StatementEntry
+		err = rows.Scan(&entry.ID, &entry.OrderNumber, &entry.Type, &entry.Amount, &entry.LedgerTotal, &entry.CreatedAt.Time, &entry.CampaignID)
+		if err != nil {
+			log.Println("Ошибка при считывании записи выписки по счёту:", err)
+			return nil, err
+		}
+
+		result = append(result, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании записей выписки по счёту:", err)
+		return nil, err
+	}
+
+	page := StatementPage{Entries: result}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		page.Entries = result[:filter.Limit]
+		last := page.Entries[len(page.Entries)-1]
+		page.Next = &StatementCursor{CreatedAt: last.CreatedAt.Time, ID: last.ID}
+	}
+
+	return &page, nil
+}
+
+func (s *databaseStorage) GetBalanceAt(user string, at *time.Time) (float32, error) {
+	ctx := context.Background()
+	var balance float32
+
+	err := s.conn.QueryRow(ctx, queryGetBalanceAt, user, at).Scan(&balance)
+	if err != nil {
+		log.Println("Ошибка при расчёте остатка на счёте пользователя "+user+":", err)
+		return 0, err
+	}
+
+	return balance, nil
+}
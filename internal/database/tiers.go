@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *databaseStorage) GetAccrualTotals(since time.Time) (map[string]float32, error) {
+	log.Printf("Получение сумм начислений пользователей с %v\n", since)
+
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetAccrualTotals, since)
+	if err != nil {
+		log.Println("Ошибка при запросе сумм начислений пользователей:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make(map[string]float32)
+
+	for rows.Next() {
+		var user string
+		var total float32
+		err = rows.Scan(&user, &total)
+		if err != nil {
+			log.Println("Ошибка при считывании суммы начислений пользователя:", err)
+			return nil, err
+		}
+
+		result[user] = total
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании сумм начислений пользователей:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) GetUserAccrualTotal(user string, since time.Time) (float32, error) {
+	ctx := context.Background()
+	var total float32
+
+	err := s.conn.QueryRow(ctx, queryGetUserAccrualTotal, user, since).Scan(&total)
+	if err != nil {
+		log.Println("Ошибка при считывании суммы начислений пользователя "+user+":", err)
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (s *databaseStorage) SaveUserTiers(tiers []UserTier, evaluatedAt time.Time) error {
+	log.Printf("Сохранение уровней для %v пользователей\n", len(tiers))
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		ctx := context.Background()
+
+		batch := &pgx.Batch{}
+		for _, tier := range tiers {
+			batch.Queue(querySaveUserTier, tier.UserLogin, tier.Tier, tier.Accrued, tier.EvaluatedAt)
+		}
+
+		err := tx.conn.SendBatch(ctx, batch).Close()
+		if err != nil {
+			log.Println("Ошибка при сохранении уровней пользователей:", err)
+			return err
+		}
+
+		ct, err := tx.conn.Exec(ctx, queryDeleteStaleUserTiers, evaluatedAt)
+		if err != nil {
+			log.Println("Ошибка при удалении устаревших уровней пользователей:", err)
+			return err
+		}
+
+		log.Printf("Сброшены уровни %v пользователей, не достигших порога\n", ct.RowsAffected())
+		return nil
+	})
+}
+
+func (s *databaseStorage) GetUserTier(user string) (*UserTier, error) {
+	ctx := context.Background()
+	var tier UserTier
+
+	row := s.conn.QueryRow(ctx, queryGetUserTier, user)
+	err := row.Scan(&tier.UserLogin, &tier.Tier, &tier.Accrued, &tier.EvaluatedAt)
+
+	if err != nil && err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании уровня пользователя "+user+":", err)
+		return nil, err
+	}
+
+	return &tier, nil
+}
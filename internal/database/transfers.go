@@ -0,0 +1,332 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	TransferStatusPending   = "PENDING"
+	TransferStatusCompleted = "COMPLETED"
+	TransferStatusExpired   = "EXPIRED"
+	TransferStatusCancelled = "CANCELLED"
+
+	transferReferencePrefix         = "TRANSFER-"
+	maxTransferConfirmationAttempts = 5
+)
+
+type Transfer struct {
+	ID          int64           `json:"id"`
+	Sender      string          `json:"sender"`
+	Recipient   string          `json:"recipient"`
+	Amount      float32         `json:"sum"`
+	Note        string          `json:"note,omitempty"`
+	Status      string          `json:"status"`
+	StatusLabel string          `json:"status_label,omitempty"`
+	CreatedAt   CustomDateTime  `json:"created_at"`
+	ExpiresAt   *CustomDateTime `json:"expires_at,omitempty"`
+	CompletedAt *CustomDateTime `json:"completed_at,omitempty"`
+}
+
+func (t *Transfer) reference() string {
+	return transferReferencePrefix + strconv.FormatInt(t.ID, 10)
+}
+
+func (s *databaseStorage) CreateTransfer(transfer *Transfer, dailyLimit float32) error {
+	log.Printf("Перевод баллов от пользователя '%v' пользователю '%v', сумма '%v'\n", transfer.Sender, transfer.Recipient, transfer.Amount)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	ctx := context.Background()
+
+	var exists bool
+	err := s.conn.QueryRow(ctx, queryCheckUserExists, transfer.Recipient).Scan(&exists)
+	if err != nil {
+		log.Println("Ошибка при проверке получателя перевода "+transfer.Recipient+":", err)
+		return err
+	}
+
+	if !exists {
+		return NewDBTransferError(0, transfer.Sender, false, true, false, false, false, errors.New("получатель перевода "+transfer.Recipient+" не найден"))
+	}
+
+	if transfer.Status == TransferStatusPending {
+		err = s.checkTransfer(transfer, dailyLimit)
+		if err != nil {
+			return err
+		}
+
+		return s.insertTransfer(transfer)
+	}
+
+	return s.executeTransfer(transfer, true, dailyLimit)
+}
+
+func (s *databaseStorage) ConfirmTransfer(user string, transferID int64, passwordConfirmed bool, dailyLimit float32) (*Transfer, error) {
+	log.Printf("Подтверждение перевода баллов '%v' пользователем '%v'\n", transferID, user)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	ctx := context.Background()
+
+	transfer, err := s.scanTransfer(s.conn.QueryRow(ctx, queryGetTransfer, transferID))
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewDBTransferError(transferID, user, true, false, false, false, false, errors.New("перевод "+strconv.FormatInt(transferID, 10)+" не найден"))
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании перевода из БД:", err)
+		return nil, err
+	}
+
+	if transfer.Sender != user {
+		return nil, NewDBTransferError(transferID, user, true, false, false, false, false, errors.New("перевод "+strconv.FormatInt(transferID, 10)+" не найден"))
+	}
+
+	if transfer.Status != TransferStatusPending {
+		return nil, NewDBTransferError(transferID, user, false, false, true, false, false, errors.New("перевод "+strconv.FormatInt(transferID, 10)+" уже находится в статусе "+transfer.Status))
+	}
+
+	now := time.Now()
+	if transfer.ExpiresAt != nil && !transfer.ExpiresAt.Time.After(now) {
+		_, err = s.conn.Exec(ctx, queryUpdateTransferStatus, transfer.ID, TransferStatusExpired, nil)
+		if err != nil {
+			log.Println("Ошибка при обновлении статуса перевода:", err)
+			return nil, err
+		}
+
+		return nil, NewDBTransferError(transferID, user, false, false, true, false, false, errors.New("срок подтверждения перевода "+strconv.FormatInt(transferID, 10)+" истёк"))
+	}
+
+	if !passwordConfirmed {
+		return nil, s.failTransferConfirmation(transfer)
+	}
+
+	err = s.executeTransfer(transfer, false, dailyLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+func (s *databaseStorage) GetTransfers(user string) ([]Transfer, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetUserTransfers, user)
+	if err != nil {
+		log.Println("Ошибка при запросе переводов пользователя:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]Transfer, 0)
+
+	for rows.Next() {
+		transfer, err := s.scanTransfer(rows)
+		if err != nil {
+			log.Println("Ошибка при считывании перевода из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, *transfer)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании переводов из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) scanTransfer(row pgx.Row) (*Transfer, error) {
+	var transfer Transfer
+	var expiresAt, completedAt *time.Time
+
+	err := row.Scan(&transfer.ID, &transfer.Sender, &transfer.Recipient, &transfer.Amount, &transfer.Note, &transfer.Status,
+		&transfer.CreatedAt.Time, &expiresAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt != nil {
+		transfer.ExpiresAt = &CustomDateTime{Time: *expiresAt}
+	}
+
+	if completedAt != nil {
+		transfer.CompletedAt = &CustomDateTime{Time: *completedAt}
+	}
+
+	return &transfer, nil
+}
+
+func (s *databaseStorage) checkTransfer(transfer *Transfer, dailyLimit float32) error {
+	err := s.refreshUserLots(transfer.Sender)
+	if err != nil {
+		return err
+	}
+
+	account, err := s.GetUserAccount(transfer.Sender)
+	if err != nil {
+		return err
+	}
+
+	if transfer.Amount > account.Balance {
+		return NewDBAccountError(transfer.Sender, true, errors.New("на счёте пользователя "+transfer.Sender+" недостаточно средств ("+strconv.FormatFloat(float64(account.Balance), 'E', -1, 32)+") для перевода "+strconv.FormatFloat(float64(transfer.Amount), 'E', -1, 32)+" баллов"))
+	}
+
+	if dailyLimit <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var transferred float32
+	err = s.conn.QueryRow(context.Background(), queryGetTransferredAmount, transfer.Sender, dayStart).Scan(&transferred)
+	if err != nil {
+		log.Println("Ошибка при подсчёте переводов пользователя "+transfer.Sender+":", err)
+		return err
+	}
+
+	if transferred+transfer.Amount > dailyLimit {
+		return NewDBTransferError(transfer.ID, transfer.Sender, false, false, false, false, true, errors.New("превышен дневной лимит переводов ("+strconv.FormatFloat(float64(dailyLimit), 'f', -1, 32)+"), сегодня уже переведено "+strconv.FormatFloat(float64(transferred), 'f', -1, 32)+" баллов"))
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) insertTransfer(transfer *Transfer) error {
+	ctx := context.Background()
+
+	var expiresAt *time.Time
+	if transfer.ExpiresAt != nil {
+		expiresAt = &transfer.ExpiresAt.Time
+	}
+
+	err := s.conn.QueryRow(ctx, queryInsertTransfer, transfer.Sender, transfer.Recipient, transfer.Amount, transfer.Note,
+		transfer.Status, transfer.CreatedAt.Time, expiresAt).Scan(&transfer.ID)
+	if err != nil {
+		log.Println("Ошибка при добавлении перевода в БД:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) failTransferConfirmation(transfer *Transfer) error {
+	var attempts int
+	var status string
+
+	err := s.conn.QueryRow(context.Background(), queryFailTransferConfirmation, transfer.ID, maxTransferConfirmationAttempts).Scan(&attempts, &status)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return NewDBTransferError(transfer.ID, transfer.Sender, false, false, true, false, false, errors.New("перевод "+strconv.FormatInt(transfer.ID, 10)+" уже завершён"))
+	}
+
+	if err != nil {
+		log.Println("Ошибка при учёте неверного пароля для подтверждения перевода:", err)
+		return err
+	}
+
+	if status == TransferStatusCancelled {
+		log.Printf("Перевод '%v' отменён после %v неверных попыток ввода пароля\n", transfer.ID, attempts)
+		return NewDBTransferError(transfer.ID, transfer.Sender, false, false, true, false, false, errors.New("перевод "+strconv.FormatInt(transfer.ID, 10)+" отменён после "+strconv.Itoa(attempts)+" неверных попыток ввода пароля"))
+	}
+
+	return NewDBTransferError(transfer.ID, transfer.Sender, false, false, false, true, false, errors.New("неверный пароль для подтверждения перевода "+strconv.FormatInt(transfer.ID, 10)+", осталось попыток: "+strconv.Itoa(maxTransferConfirmationAttempts-attempts)))
+}
+
+func (s *databaseStorage) executeTransfer(transfer *Transfer, isNew bool, dailyLimit float32) error {
+	return s.inTransaction(func(tx *databaseStorage) error {
+		err := tx.checkTransfer(transfer, dailyLimit)
+		if err != nil {
+			return err
+		}
+
+		return tx.completeTransfer(transfer, isNew)
+	})
+}
+
+func (s *databaseStorage) completeTransfer(transfer *Transfer, isNew bool) error {
+	ctx := context.Background()
+	now := time.Now()
+	transfer.Status = TransferStatusCompleted
+	transfer.CompletedAt = &CustomDateTime{Time: now}
+
+	if isNew {
+		err := s.insertTransfer(transfer)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := s.conn.Exec(ctx, queryUpdateTransferStatus, transfer.ID, transfer.Status, now)
+	if err != nil {
+		log.Println("Ошибка при обновлении статуса перевода:", err)
+		return err
+	}
+
+	sender, err := s.GetUserAccount(transfer.Sender)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.consumeLots(transfer.Sender, "", transfer.Amount)
+	if err != nil {
+		return err
+	}
+
+	sender.Balance -= transfer.Amount
+
+	err = s.UpdateUserAccount(sender)
+	if err != nil {
+		return err
+	}
+
+	err = s.AddTransaction(&Transaction{
+		OrderNumber: transfer.reference(),
+		UserLogin:   transfer.Sender,
+		Type:        TransactionTypeTransferOut,
+		Amount:      transfer.Amount,
+		CreatedAt:   CustomDateTime{Time: now},
+	})
+	if err != nil {
+		return err
+	}
+
+	recipient, err := s.GetUserAccount(transfer.Recipient)
+	if err != nil {
+		return err
+	}
+
+	credited := recipient.credit(transfer.Amount)
+	if credited > 0 {
+		err = s.addLot(transfer.Recipient, transfer.reference(), credited, now, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.UpdateUserAccount(recipient)
+	if err != nil {
+		return err
+	}
+
+	return s.AddTransaction(&Transaction{
+		OrderNumber: transfer.reference(),
+		UserLogin:   transfer.Recipient,
+		Type:        TransactionTypeTransferIn,
+		Amount:      transfer.Amount,
+		CreatedAt:   CustomDateTime{Time: now},
+	})
+}
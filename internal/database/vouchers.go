@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const voucherReferencePrefix = "VOUCHER-"
+
+type VoucherBatch struct {
+	ID        int64           `json:"id"`
+	Name      string          `json:"name,omitempty"`
+	Value     float32         `json:"value"`
+	Quantity  int32           `json:"quantity"`
+	ExpiresAt *CustomDateTime `json:"expires_at,omitempty"`
+	CreatedBy string          `json:"created_by,omitempty"`
+	CreatedAt CustomDateTime  `json:"created_at"`
+	Redeemed  int32           `json:"redeemed"`
+	Codes     []string        `json:"codes,omitempty"`
+}
+
+type Voucher struct {
+	Code       string
+	BatchID    int64
+	Value      float32
+	RedeemedBy string
+	RedeemedAt time.Time
+}
+
+func (s *databaseStorage) CreateVoucherBatch(batch *VoucherBatch, codes []string) error {
+	log.Printf("Создание партии из '%v' ваучеров номиналом '%v'\n", len(codes), batch.Value)
+
+	return s.inTransaction(func(tx *databaseStorage) error {
+		ctx := context.Background()
+
+		err := tx.conn.QueryRow(ctx, queryInsertVoucherBatch, batch.Name, batch.Value, batch.Quantity,
+			optionalTime(batch.ExpiresAt), batch.CreatedBy, batch.CreatedAt.Time).Scan(&batch.ID)
+		if err != nil {
+			log.Println("Ошибка при добавлении партии ваучеров в БД:", err)
+			return err
+		}
+
+		rows := make([][]any, 0, len(codes))
+		for _, code := range codes {
+			rows = append(rows, []any{code, batch.ID, batch.Value, optionalTime(batch.ExpiresAt)})
+		}
+
+		var pgErr *pgconn.PgError
+		_, err = tx.conn.CopyFrom(ctx, pgx.Identifier{"public", "vouchers"},
+			[]string{"code", "batch_id", "value", "expires_at"}, pgx.CopyFromRows(rows))
+		if err != nil && errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return NewDBVoucherError("", false, false, false, true, err)
+		}
+
+		if err != nil {
+			log.Println("Ошибка при добавлении ваучеров в БД:", err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *databaseStorage) GetVoucherBatches() ([]VoucherBatch, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetVoucherBatches)
+	if err != nil {
+		log.Println("Ошибка при запросе партий ваучеров:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]VoucherBatch, 0)
+
+	for rows.Next() {
+		var batch VoucherBatch
+		var expiresAt *time.Time
+
+		err = rows.Scan(&batch.ID, &batch.Name, &batch.Value, &batch.Quantity, &expiresAt, &batch.CreatedBy,
+			&batch.CreatedAt.Time, &batch.Redeemed)
+		if err != nil {
+			log.Println("Ошибка при считывании партии ваучеров из списка:", err)
+			return nil, err
+		}
+
+		if expiresAt != nil {
+			batch.ExpiresAt = &CustomDateTime{Time: *expiresAt}
+		}
+
+		result = append(result, batch)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании партий ваучеров из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) RedeemVoucher(user, code string) (*Voucher, error) {
+	log.Printf("Активация ваучера пользователем '%v'\n", user)
+
+	s.locker.account.Lock()
+	defer s.locker.account.Unlock()
+
+	ctx := context.Background()
+	now := time.Now()
+	voucher := Voucher{Code: code, RedeemedBy: user, RedeemedAt: now}
+
+	err := s.inTransaction(func(tx *databaseStorage) error {
+		err := tx.conn.QueryRow(ctx, queryRedeemVoucher, code, user, now).Scan(&voucher.BatchID, &voucher.Value)
+		if err != nil && errors.Is(err, pgx.ErrNoRows) {
+			return tx.voucherError(code, user, now)
+		}
+
+		if err != nil {
+			log.Println("Ошибка при активации ваучера:", err)
+			return err
+		}
+
+		return tx.creditBonus(&Transaction{
+			OrderNumber: voucherReferencePrefix + code,
+			UserLogin:   user,
+			Type:        TransactionTypeVoucher,
+			Amount:      voucher.Value,
+			CreatedAt:   CustomDateTime{Time: now},
+		}, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &voucher, nil
+}
+
+func (s *databaseStorage) voucherError(code, user string, now time.Time) error {
+	ctx := context.Background()
+
+	var batchID int64
+	var value float32
+	var expiresAt *time.Time
+	var redeemedBy string
+
+	err := s.conn.QueryRow(ctx, queryGetVoucher, code).Scan(&batchID, &value, &expiresAt, &redeemedBy)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return NewDBVoucherError(code, true, false, false, false, errors.New("ваучер не найден"))
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании ваучера:", err)
+		return err
+	}
+
+	if redeemedBy != "" {
+		return NewDBVoucherError(code, false, true, false, false, errors.New("ваучер уже активирован"))
+	}
+
+	if expiresAt != nil && !expiresAt.After(now) {
+		return NewDBVoucherError(code, false, false, true, false, errors.New("срок действия ваучера истёк"))
+	}
+
+	return NewDBVoucherError(code, true, false, false, false, errors.New("ваучер не найден"))
+}
+
+func (s *databaseStorage) AddVoucherAttempt(user, ip string, success bool) error {
+	ctx := context.Background()
+
+	_, err := s.conn.Exec(ctx, queryInsertVoucherAttempt, user, ip, success, time.Now())
+	if err != nil {
+		log.Println("Ошибка при сохранении попытки активации ваучера:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) CountFailedVoucherAttempts(user, ip string, since time.Time) (int, int, error) {
+	ctx := context.Background()
+	var byUser, byIP int
+
+	err := s.conn.QueryRow(ctx, queryCountFailedVoucherAttempts, user, ip, since).Scan(&byUser, &byIP)
+	if err != nil {
+		log.Println("Ошибка при подсчёте неудачных попыток активации ваучеров:", err)
+		return 0, 0, err
+	}
+
+	return byUser, byIP, nil
+}
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryFailed    = "FAILED"
+)
+
+type Webhook struct {
+	ID           int64           `json:"id"`
+	UserLogin    string          `json:"-"`
+	URL          string          `json:"url"`
+	Secret       string          `json:"secret,omitempty"`
+	EventTypes   []string        `json:"event_types"`
+	Active       bool            `json:"active"`
+	FailureCount int             `json:"failure_count"`
+	DisabledAt   *CustomDateTime `json:"disabled_at,omitempty"`
+	CreatedAt    CustomDateTime  `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	EventID       int64           `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       string          `json:"-"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	NextAttemptAt CustomDateTime  `json:"next_attempt_at"`
+	CreatedAt     CustomDateTime  `json:"created_at"`
+	DeliveredAt   *CustomDateTime `json:"delivered_at,omitempty"`
+	URL           string          `json:"-"`
+	Secret        string          `json:"-"`
+}
+
+func optionalDateTime(t *time.Time) *CustomDateTime {
+	if t == nil {
+		return nil
+	}
+
+	return &CustomDateTime{Time: *t}
+}
+
+func scanWebhook(row pgx.Row) (*Webhook, error) {
+	var webhook Webhook
+	var disabledAt *time.Time
+
+	err := row.Scan(&webhook.ID, &webhook.UserLogin, &webhook.URL, &webhook.Secret, &webhook.EventTypes, &webhook.Active,
+		&webhook.FailureCount, &disabledAt, &webhook.CreatedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.DisabledAt = optionalDateTime(disabledAt)
+
+	return &webhook, nil
+}
+
+func (s *databaseStorage) CreateWebhook(webhook *Webhook) error {
+	log.Printf("Добавление в БД вебхука '%v' пользователя '%v'\n", webhook.URL, webhook.UserLogin)
+
+	ctx := context.Background()
+
+	err := s.conn.QueryRow(ctx, queryInsertWebhook, webhook.UserLogin, webhook.URL, webhook.Secret, webhook.EventTypes,
+		webhook.Active, webhook.CreatedAt.Time).Scan(&webhook.ID)
+	if err != nil {
+		log.Println("Ошибка при добавлении вебхука в БД:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) GetWebhooks(user string) ([]Webhook, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetWebhooks, user)
+	if err != nil {
+		log.Println("Ошибка при запросе вебхуков пользователя:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]Webhook, 0)
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			log.Println("Ошибка при считывании вебхука пользователя из списка:", err)
+			return nil, err
+		}
+
+		result = append(result, *webhook)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании вебхуков пользователя из списка:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) GetWebhook(user string, webhookID int64) (*Webhook, error) {
+	ctx := context.Background()
+
+	webhook, err := scanWebhook(s.conn.QueryRow(ctx, queryGetWebhook, webhookID, user))
+	if err != nil && err == pgx.ErrNoRows {
+		log.Println("Вебхук " + strconv.FormatInt(webhookID, 10) + " пользователя " + user + " не найден")
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("Ошибка при считывании вебхука "+strconv.FormatInt(webhookID, 10)+" из БД:", err)
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (s *databaseStorage) UpdateWebhook(webhook *Webhook) error {
+	log.Printf("Обновление вебхука '%v' пользователя '%v'\n", webhook.ID, webhook.UserLogin)
+
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryUpdateWebhook, webhook.ID, webhook.UserLogin, webhook.URL, webhook.EventTypes, webhook.Active, time.Now())
+	if err != nil {
+		log.Println("Ошибка при обновлении вебхука в БД:", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return NewDBWebhookError(webhook.ID, true, errors.New("вебхук "+strconv.FormatInt(webhook.ID, 10)+" не найден"))
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) DeleteWebhook(user string, webhookID int64) error {
+	log.Printf("Удаление вебхука '%v' пользователя '%v'\n", webhookID, user)
+
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryDeleteWebhook, webhookID, user)
+	if err != nil {
+		log.Println("Ошибка при удалении вебхука из БД:", err)
+		return err
+	}
+
+	if ct.RowsAffected() == 0 {
+		return NewDBWebhookError(webhookID, true, errors.New("вебхук "+strconv.FormatInt(webhookID, 10)+" не найден"))
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) AddWebhookDeliveries(event *Event) (int64, error) {
+	ctx := context.Background()
+
+	ct, err := s.conn.Exec(ctx, queryInsertWebhookDeliveries, event.UserLogin, event.ID, event.Type, event.Payload, event.CreatedAt)
+	if err != nil {
+		log.Println("Ошибка при постановке события "+strconv.FormatInt(event.ID, 10)+" в очередь доставки вебхуков:", err)
+		return 0, err
+	}
+
+	return ct.RowsAffected(), nil
+}
+
+func (s *databaseStorage) GetDueWebhookDeliveries(at time.Time, limit int) ([]WebhookDelivery, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetDueWebhookDeliveries, at, limit)
+	if err != nil {
+		log.Println("Ошибка при запросе очереди доставки вебхуков:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]WebhookDelivery, 0)
+
+	for rows.Next() {
+		var delivery WebhookDelivery
+		var deliveredAt *time.Time
+
+		err = rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &delivery.Payload, &delivery.Status,
+			&delivery.Attempts, &delivery.ResponseCode, &delivery.Error, &delivery.NextAttemptAt.Time, &delivery.CreatedAt.Time,
+			&deliveredAt, &delivery.URL, &delivery.Secret)
+		if err != nil {
+			log.Println("Ошибка при считывании доставки вебхука из очереди:", err)
+			return nil, err
+		}
+
+		delivery.DeliveredAt = optionalDateTime(deliveredAt)
+		result = append(result, delivery)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании очереди доставки вебхуков:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *databaseStorage) UpdateWebhookDelivery(delivery *WebhookDelivery) error {
+	ctx := context.Background()
+
+	_, err := s.conn.Exec(ctx, queryUpdateWebhookDelivery, delivery.ID, delivery.Status, delivery.Attempts, delivery.ResponseCode,
+		delivery.Error, delivery.NextAttemptAt.Time, optionalTime(delivery.DeliveredAt))
+	if err != nil {
+		log.Println("Ошибка при обновлении доставки вебхука "+strconv.FormatInt(delivery.ID, 10)+":", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *databaseStorage) RecordWebhookResult(webhookID int64, success bool, disableAfter int) (bool, error) {
+	ctx := context.Background()
+
+	var active bool
+	var err error
+
+	if success {
+		err = s.conn.QueryRow(ctx, queryResetWebhookFailures, webhookID).Scan(&active)
+	} else {
+		err = s.conn.QueryRow(ctx, queryAddWebhookFailure, webhookID, disableAfter, time.Now()).Scan(&active)
+	}
+
+	if err != nil && err == pgx.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		log.Println("Ошибка при учёте результата доставки вебхука "+strconv.FormatInt(webhookID, 10)+":", err)
+		return false, err
+	}
+
+	return active, nil
+}
+
+func (s *databaseStorage) GetWebhookDeliveries(webhookID int64, limit int) ([]WebhookDelivery, error) {
+	ctx := context.Background()
+
+	rows, err := s.conn.Query(ctx, queryGetWebhookDeliveries, webhookID, limit)
+	if err != nil {
+		log.Println("Ошибка при запросе журнала доставки вебхука:", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make([]WebhookDelivery, 0)
+
+	for rows.Next() {
+		var delivery WebhookDelivery
+		var deliveredAt *time.Time
+
+		err = rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &delivery.Payload, &delivery.Status,
+			&delivery.Attempts, &delivery.ResponseCode, &delivery.Error, &delivery.NextAttemptAt.Time, &delivery.CreatedAt.Time, &deliveredAt)
+		if err != nil {
+			log.Println("Ошибка при считывании записи журнала доставки вебхука:", err)
+			return nil, err
+		}
+
+		delivery.DeliveredAt = optionalDateTime(deliveredAt)
+		result = append(result, delivery)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Println("Ошибка при считывании журнала доставки вебхука:", err)
+		return nil, err
+	}
+
+	return result, nil
+}
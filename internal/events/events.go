@@ -0,0 +1,226 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	EventOrderStatusChanged = "order_status_changed"
+	EventPointsCredited     = "points_credited"
+	EventWithdrawalMade     = "withdrawal_made"
+
+	EventTransferConfirmationRequested = "transfer_confirmation_requested"
+
+	subscriberBufferSize   = 32
+	backlogPageSize        = 500
+	eventTTL               = 7 * 24 * time.Hour
+	delayForExpiringEvents = 60 * 60
+)
+
+var EventTypes = []string{EventOrderStatusChanged, EventPointsCredited, EventWithdrawalMade, EventTransferConfirmationRequested}
+
+type OrderStatusChanged struct {
+	Order          string `json:"order"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+}
+
+type PointsCredited struct {
+	Order  string  `json:"order"`
+	Amount float32 `json:"amount"`
+}
+
+type WithdrawalMade struct {
+	Order string  `json:"order"`
+	Sum   float32 `json:"sum"`
+}
+
+type TransferConfirmationRequested struct {
+	TransferID int64     `json:"transfer_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type Publisher interface {
+	Publish(user, eventType string, payload any) error
+}
+
+type Listener interface {
+	HandleEvent(event *database.Event)
+}
+
+type Bus interface {
+	Publisher
+	AddListener(listener Listener)
+	Subscribe(user string) *Subscription
+	GetEventsAfter(user string, lastEventID int64) ([]database.Event, error)
+	Close()
+}
+
+type Subscription struct {
+	Events <-chan database.Event
+
+	user   string
+	events chan database.Event
+	bus    *eventBus
+}
+
+type eventBus struct {
+	model database.Storager
+	done  chan struct{}
+
+	lock        sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+	listeners   []Listener
+}
+
+func NewEvents(m database.Storager) (Bus, error) {
+	if m == nil {
+		return nil, errors.New("не задано хранилище событий")
+	}
+
+	result := &eventBus{
+		model:       m,
+		done:        make(chan struct{}),
+		subscribers: make(map[string]map[*Subscription]struct{}),
+	}
+
+	go result.expireEvents()
+
+	return result, nil
+}
+
+func (b *eventBus) Publish(user, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event := database.Event{
+		UserLogin: user,
+		Type:      eventType,
+		Payload:   string(data),
+		CreatedAt: time.Now(),
+	}
+
+	err = b.model.AddEvent(&event)
+	if err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+
+	for subscription := range b.subscribers[user] {
+		select {
+		case subscription.events <- event:
+		default:
+			log.Println("Очередь событий подписчика пользователя '" + user + "' переполнена, подписка закрыта")
+			b.unsubscribe(subscription)
+		}
+	}
+
+	listeners := b.listeners
+	b.lock.Unlock()
+
+	for _, listener := range listeners {
+		go listener.HandleEvent(&event)
+	}
+
+	return nil
+}
+
+func (b *eventBus) AddListener(listener Listener) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.listeners = append(b.listeners, listener)
+}
+
+func (b *eventBus) Subscribe(user string) *Subscription {
+	events := make(chan database.Event, subscriberBufferSize)
+	subscription := &Subscription{
+		Events: events,
+		user:   user,
+		events: events,
+		bus:    b,
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.subscribers[user] == nil {
+		b.subscribers[user] = make(map[*Subscription]struct{})
+	}
+
+	b.subscribers[user][subscription] = struct{}{}
+
+	return subscription
+}
+
+func (s *Subscription) Cancel() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+
+	s.bus.unsubscribe(s)
+}
+
+func (b *eventBus) unsubscribe(subscription *Subscription) {
+	subscribers, ok := b.subscribers[subscription.user]
+	if !ok {
+		return
+	}
+
+	if _, ok = subscribers[subscription]; !ok {
+		return
+	}
+
+	delete(subscribers, subscription)
+	close(subscription.events)
+
+	if len(subscribers) == 0 {
+		delete(b.subscribers, subscription.user)
+	}
+}
+
+func (b *eventBus) GetEventsAfter(user string, lastEventID int64) ([]database.Event, error) {
+	result := make([]database.Event, 0)
+
+	for {
+		events, err := b.model.GetEventsAfter(user, lastEventID, backlogPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, events...)
+		if len(events) < backlogPageSize {
+			return result, nil
+		}
+
+		lastEventID = events[len(events)-1].ID
+	}
+}
+
+func (b *eventBus) Close() {
+	close(b.done)
+}
+
+func (b *eventBus) expireEvents() {
+	select {
+	case <-b.done:
+		return
+	default:
+
+	}
+
+	err := b.model.DeleteExpiredEvents(time.Now().Add(-eventTTL))
+	if err != nil {
+		log.Println("Ошибка при удалении устаревших событий:", err)
+	}
+
+	time.AfterFunc(time.Second*delayForExpiringEvents, func() { b.expireEvents() })
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+)
+
+const csvFlushRows = 100
+
+var csvHeader = []string{"section", "date", "type", "order", "amount", "ledger_total", "status"}
+
+func (s *Statement) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+
+	rows := 0
+	err = s.walk(func(r row) error {
+		date := ""
+		if !r.date.IsZero() {
+			date = r.date.Format(time.RFC3339)
+		}
+
+		err := writer.Write([]string{r.section, date, r.transactionType, r.order, formatAmount(r.amount), formatAmount(r.balance), r.status})
+		if err != nil {
+			return err
+		}
+
+		rows++
+		if rows%csvFlushRows == 0 {
+			writer.Flush()
+			return writer.Error()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
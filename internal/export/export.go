@@ -0,0 +1,153 @@
+package export
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	FormatCSV = "csv"
+	FormatPDF = "pdf"
+
+	exportPageSize = 500
+
+	sectionOpening = "opening_ledger"
+	sectionOrder   = "order"
+	sectionEntry   = "entry"
+	sectionClosing = "closing_ledger"
+)
+
+type Source interface {
+	GetOrders(user string, filter *database.OrderFilter) (*database.OrderPage, error)
+	GetStatement(user string, filter *database.StatementFilter) (*database.StatementPage, error)
+	GetBalanceAt(user string, at *time.Time) (float32, error)
+}
+
+type Period struct {
+	From *time.Time
+	To   *time.Time
+}
+
+type Statement struct {
+	src     Source
+	user    string
+	period  Period
+	opening float32
+}
+
+type row struct {
+	section         string
+	date            time.Time
+	transactionType string
+	order           string
+	amount          *float32
+	balance         *float32
+	status          string
+}
+
+func ContentType(format string) (string, error) {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8", nil
+	case FormatPDF:
+		return "application/pdf", nil
+	default:
+		return "", errors.New("неподдерживаемый формат выписки '" + format + "'")
+	}
+}
+
+func NewStatement(src Source, user string, period Period) (*Statement, error) {
+	opening, err := src.GetBalanceAt(user, period.From)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{
+		src:     src,
+		user:    user,
+		period:  period,
+		opening: opening,
+	}, nil
+}
+
+func (s *Statement) walk(emit func(row) error) error {
+	src, user, period := s.src, s.user, s.period
+
+	openingDate := time.Time{}
+	if period.From != nil {
+		openingDate = *period.From
+	}
+
+	opening := s.opening
+	err := emit(row{section: sectionOpening, date: openingDate, balance: &opening})
+	if err != nil {
+		return err
+	}
+
+	orderFilter := database.OrderFilter{From: period.From, To: period.To, Limit: exportPageSize}
+	for {
+		page, err := src.GetOrders(user, &orderFilter)
+		if err != nil {
+			return err
+		}
+
+		for i := range page.Orders {
+			order := page.Orders[i]
+			err = emit(row{section: sectionOrder, date: order.UploadedAt.Time, order: order.ID, amount: &order.Accrual, status: order.Status})
+			if err != nil {
+				return err
+			}
+		}
+
+		if page.Next == nil {
+			break
+		}
+
+		orderFilter.After = page.Next
+	}
+
+	statementFilter := database.StatementFilter{From: period.From, To: period.To, Limit: exportPageSize}
+	for {
+		page, err := src.GetStatement(user, &statementFilter)
+		if err != nil {
+			return err
+		}
+
+		for i := range page.Entries {
+			entry := page.Entries[i]
+			err = emit(row{section: sectionEntry, date: entry.CreatedAt.Time, transactionType: entry.Type, order: entry.OrderNumber, amount: &entry.Amount, balance: &entry.LedgerTotal})
+			if err != nil {
+				return err
+			}
+		}
+
+		if page.Next == nil {
+			break
+		}
+
+		statementFilter.After = page.Next
+	}
+
+	closing, err := src.GetBalanceAt(user, period.To)
+	if err != nil {
+		return err
+	}
+
+	closingDate := time.Now()
+	if period.To != nil {
+		closingDate = *period.To
+	}
+
+	return emit(row{section: sectionClosing, date: closingDate, balance: &closing})
+}
+
+func formatAmount(amount *float32) string {
+	if amount == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(float64(*amount), 'f', 2, 32)
+}
@@ -0,0 +1,173 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+const (
+	pdfLinesPerPage = 64
+	pdfFontSize     = 8
+	pdfLeading      = 11
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfDateLayout   = "2006-01-02 15:04"
+)
+
+var pdfTransliteration = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'ә': "a", 'ғ': "gh", 'қ': "q", 'ң': "ng", 'ө': "o", 'ұ': "u", 'ү': "u",
+	'һ': "h", 'і': "i",
+}
+
+func (s *Statement) RenderPDF() ([]byte, error) {
+	lines := make([]string, 0)
+
+	err := s.walk(func(r row) error {
+		lines = append(lines, formatPDFRow(r))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	title := "Loyalty account statement: " + s.user + ", period " + formatPDFPeriod(s.period)
+	header := fmt.Sprintf("%-16s %-16s %-12s %-20s %10s %10s %-10s", "SECTION", "DATE", "TYPE", "ORDER", "AMOUNT", "LEDGER", "STATUS")
+
+	return renderPDF(title, header, lines), nil
+}
+
+func formatPDFRow(r row) string {
+	date := ""
+	if !r.date.IsZero() {
+		date = r.date.Format(pdfDateLayout)
+	}
+
+	return fmt.Sprintf("%-16s %-16s %-12s %-20s %10s %10s %-10s", r.section, date, r.transactionType, r.order, formatAmount(r.amount), formatAmount(r.balance), r.status)
+}
+
+func formatPDFPeriod(period Period) string {
+	from, to := "...", time.Now().Format(pdfDateLayout)
+
+	if period.From != nil {
+		from = period.From.Format(pdfDateLayout)
+	}
+
+	if period.To != nil {
+		to = period.To.Format(pdfDateLayout)
+	}
+
+	return from + " - " + to
+}
+
+func renderPDF(title, header string, lines []string) []byte {
+	pages := make([][]string, 0)
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	var buf bytes.Buffer
+	offsets := make([]int, 0)
+
+	addObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, 0, len(pages))
+	for i := range pages {
+		kids = append(kids, strconv.Itoa(4+2*i)+" 0 R")
+	}
+
+	addObject("<< /Type /Catalog /Pages 2 0 R >>")
+	addObject("<< /Type /Pages /Kids [" + strings.Join(kids, " ") + "] /Count " + strconv.Itoa(len(pages)) + " >>")
+	addObject("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		fmt.Fprintf(&content, "(%s) Tj T* T*\n", escapePDFText(title))
+		fmt.Fprintf(&content, "(%s) Tj T*\n", escapePDFText(header))
+
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", escapePDFText(line))
+		}
+
+		fmt.Fprintf(&content, "T* (%s) Tj\nET", escapePDFText(fmt.Sprintf("Page %d of %d", i+1, len(pages))))
+
+		addObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*i))
+		addObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	addObject("<< /Title " + encodePDFTextString(title) + " >>")
+	info := len(offsets)
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, info, xref)
+
+	return buf.Bytes()
+}
+
+func escapePDFText(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteString(transliterate(r))
+		}
+	}
+
+	return b.String()
+}
+
+func transliterate(r rune) string {
+	latin, ok := pdfTransliteration[unicode.ToLower(r)]
+	if !ok {
+		return "?"
+	}
+
+	if latin == "" || !unicode.IsUpper(r) {
+		return latin
+	}
+
+	return strings.ToUpper(latin[:1]) + latin[1:]
+}
+
+func encodePDFTextString(s string) string {
+	var b strings.Builder
+
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+
+	return b.String()
+}
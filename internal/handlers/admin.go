@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type OrderStatusRequestBody struct {
+	Status string `json:"status"`
+}
+
+func (h *Handler) authorizeAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if currentUserLogin(r) == "" {
+			log.Println("Пользователь не аутентифицирован")
+			writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+			return
+		}
+
+		if _, ok := h.admins[currentUserLogin(r)]; !ok {
+			log.Println("Пользователь " + currentUserLogin(r) + " не является администратором")
+			writeErrorResponse(w, http.StatusForbidden, ErrorCodeForbidden, nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) changeOrderStatus(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "number")
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе изменения статуса заказа:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	requestBody := OrderStatusRequestBody{}
+	err = json.Unmarshal(request, &requestBody)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе изменения статуса заказа:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	log.Println("Администратор " + currentUserLogin(r) + " изменяет статус заказа " + orderID + " на " + requestBody.Status)
+
+	err = h.orders.ChangeOrderStatus(orderID, requestBody.Status)
+	if err != nil {
+		log.Println("Ошибка при изменении статуса заказа: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) getAdminOrderHistory(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "number")
+	history, err := h.orders.GetOrderHistory(orderID)
+	writeOrderHistory(w, orderID, history, err)
+}
+
+func (h *Handler) recheckOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "number")
+
+	log.Println("Администратор " + currentUserLogin(r) + " запросил повторную проверку заказа " + orderID)
+
+	err := h.orders.RecheckOrder(orderID)
+	if err != nil {
+		log.Println("Ошибка при постановке заказа на повторную проверку: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -2,28 +2,36 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
-	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/orders"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
 	"log"
 	"net/http"
 )
 
 type WithdrawRequestBody struct {
-	OrderID string  `json:"order"`
-	Amount  float32 `json:"sum"`
+	OrderID    string  `json:"order"`
+	Amount     float32 `json:"sum"`
+	OrderTotal float32 `json:"order_total,omitempty"`
+}
+
+type TransactionResponseBody struct {
+	OrderNumber string                  `json:"order"`
+	Type        string                  `json:"type"`
+	Amount      float32                 `json:"sum"`
+	CreatedAt   database.CustomDateTime `json:"processed_at"`
+	CampaignID  int64                   `json:"campaign_id,omitempty"`
 }
 
 func (h *Handler) getBalance(w http.ResponseWriter, r *http.Request) {
-	if h.currentUserLogin == "" {
+	if currentUserLogin(r) == "" {
 		log.Println("Пользователь не аутентифицирован")
-		http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
 		return
 	}
 
-	account, err := h.orders.GetUserAccount(h.currentUserLogin)
+	account, err := h.orders.GetUserAccount(currentUserLogin(r))
 	if err != nil {
 		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -32,7 +40,7 @@ func (h *Handler) getBalance(w http.ResponseWriter, r *http.Request) {
 	response, err := json.Marshal(account)
 	if err != nil {
 		log.Println("Ошибка при формировании ответа:", err)
-		http.Error(w, "ошибка при формировании ответа: "+err.Error(), http.StatusInternalServerError)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
 		return
 	}
 
@@ -45,16 +53,16 @@ func (h *Handler) getBalance(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) withdrawPoints(w http.ResponseWriter, r *http.Request) {
-	if h.currentUserLogin == "" {
+	if currentUserLogin(r) == "" {
 		log.Println("Пользователь не аутентифицирован")
-		http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
 		return
 	}
 
 	request, err := decodeRequest(r)
 	if err != nil {
 		log.Println("Неверный формат данных в запросе добавления заказа:", err)
-		http.Error(w, "неверный формат данных в запросе добавления заказа: "+err.Error(), http.StatusBadRequest)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
 		return
 	}
 
@@ -62,64 +70,96 @@ func (h *Handler) withdrawPoints(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(request, &requestBody)
 	if err != nil {
 		log.Println("Неверный формат данных в запросе на списание средств:", err)
-		http.Error(w, "неверный формат данных в запросе на списание средств: "+err.Error(), http.StatusBadRequest)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
 		return
 	}
 
 	log.Println("Переданные данные для списания средств:", requestBody)
 
-	var orderError *orders.OrderError
-	err = h.orders.WithdrawForOrder(h.currentUserLogin, requestBody.OrderID, requestBody.Amount)
-
-	if err != nil && errors.As(err, &orderError) {
+	err = h.orders.WithdrawForOrder(currentUserLogin(r), requestBody.OrderID, currentMerchant(r), requestBody.Amount, requestBody.OrderTotal)
+	if err != nil {
 		log.Println("Ошибка при обработке запроса на списание средств: " + err.Error())
+		writeError(w, err)
+		return
+	}
 
-		switch {
-		case orderError.IncorrectID:
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		case orderError.InsufficientFunds:
-			http.Error(w, err.Error(), http.StatusPaymentRequired)
-		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	w.WriteHeader(http.StatusOK)
+}
 
+func (h *Handler) getWithdrawals(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
 		return
 	}
 
+	transactions, err := h.orders.GetUserWithdrawals(currentUserLogin(r))
 	if err != nil {
-		log.Println("Ошибка при обработке запроса на списание средств: " + err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println("Ошибка при обработке запроса на получение списка списаний: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	if len(transactions) == 0 {
+		log.Println("Для пользователя " + currentUserLogin(r) + " не найдено списаний")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(transactions)
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
 }
 
-func (h *Handler) getWithdrawals(w http.ResponseWriter, r *http.Request) {
-	if h.currentUserLogin == "" {
+func (h *Handler) getTransactions(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
 		log.Println("Пользователь не аутентифицирован")
-		http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
 		return
 	}
 
-	transactions, err := h.orders.GetUserWithdrawals(h.currentUserLogin)
+	transactions, err := h.orders.GetUserTransactions(currentUserLogin(r))
 	if err != nil {
-		log.Println("Ошибка при обработке запроса на получение списка списаний: " + err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println("Ошибка при обработке запроса на получение истории операций: " + err.Error())
+		writeError(w, err)
 		return
 	}
 
 	if len(transactions) == 0 {
-		log.Println("Для пользователя " + h.currentUserLogin + " не найдено списаний")
-		http.Error(w, "Для пользователя "+h.currentUserLogin+" не найдено списаний", http.StatusNoContent)
+		log.Println("Для пользователя " + currentUserLogin(r) + " не найдено операций")
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	responseBody := make([]TransactionResponseBody, 0, len(transactions))
+	for _, transaction := range transactions {
+		responseBody = append(responseBody, TransactionResponseBody{
+			OrderNumber: transaction.OrderNumber,
+			Type:        transaction.Type,
+			Amount:      transaction.Amount,
+			CreatedAt:   transaction.CreatedAt,
+			CampaignID:  transaction.CampaignID,
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
-	response, err := json.Marshal(transactions)
+	response, err := json.Marshal(responseBody)
 	if err != nil {
 		log.Println("Ошибка при формировании ответа:", err)
-		http.Error(w, "ошибка при формировании ответа: "+err.Error(), http.StatusInternalServerError)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
 		return
 	}
 
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/orders"
+)
+
+func (o *fakeOrders) CreateHold(user, orderID string, amount, orderTotal float32, ttl time.Duration) (*database.BalanceHold, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if amount > o.balances[user]-o.held[user] {
+		return nil, orders.NewOrderError(orderID, false, false, true, user, errors.New("недостаточно средств для резервирования"))
+	}
+
+	o.held[user] += amount
+
+	now := time.Now()
+	return &database.BalanceHold{
+		ID:          1,
+		UserLogin:   user,
+		OrderNumber: orderID,
+		Amount:      amount,
+		Status:      database.HoldStatusActive,
+		CreatedAt:   database.CustomDateTime{Time: now},
+		ExpiresAt:   database.CustomDateTime{Time: now.Add(ttl)},
+	}, nil
+}
+
+func (o *fakeOrders) WithdrawForOrder(user, orderID, merchant string, amount, orderTotal float32) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if amount <= 0 {
+		return orders.NewWithdrawalRuleError(orderID, user, orders.WithdrawalRulePositiveAmount, 0, amount, errors.New("сумма списания должна быть больше нуля"))
+	}
+
+	var spent float32
+	for _, withdrawal := range o.withdrawals {
+		if withdrawal.user == user {
+			spent += withdrawal.amount
+		}
+	}
+
+	if o.dailyLimit > 0 && spent+amount > o.dailyLimit {
+		return orders.NewWithdrawalRuleError(orderID, user, orders.WithdrawalRuleDailyLimit, o.dailyLimit, spent+amount, errors.New("превышен дневной лимит списаний"))
+	}
+
+	if amount > o.balances[user]-o.held[user] {
+		return orders.NewOrderError(orderID, false, false, true, user, errors.New("недостаточно средств для списания"))
+	}
+
+	o.balances[user] -= amount
+	o.withdrawals = append(o.withdrawals, fakeWithdrawal{user: user, order: orderID, merchant: merchant, amount: amount})
+
+	return nil
+}
+
+func TestWithdrawWithHolds(t *testing.T) {
+	o := newFakeOrders(map[string]float32{alice.login: 100, bob.login: 20})
+	h := newTestHandler(o, nil)
+
+	response := doRequest(h, http.MethodPost, "/api/user/balance/holds", &alice, `{"order":"12345678903","sum":70,"ttl":600}`, nil)
+	checkResponse(t, response, http.StatusCreated, "")
+
+	tests := []struct {
+		name       string
+		user       *testUser
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "без аутентификации",
+			body:       `{"order":"2377225624","sum":10}`,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   ErrorCodeUnauthorized,
+		},
+		{
+			name:       "неверный формат запроса",
+			user:       &alice,
+			body:       `{"order":`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrorCodeBadRequest,
+		},
+		{
+			name:       "списание зарезервированных баллов",
+			user:       &alice,
+			body:       `{"order":"2377225624","sum":50}`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   ErrorCodeInsufficientFunds,
+		},
+		{
+			name:       "списание свободного остатка",
+			user:       &alice,
+			body:       `{"order":"2377225624","sum":30}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "резерв другого пользователя не влияет на списание",
+			user:       &bob,
+			body:       `{"order":"79927398713","sum":20}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := doRequest(h, http.MethodPost, "/api/user/balance/withdraw", tt.user, tt.body, nil)
+			checkResponse(t, response, tt.wantStatus, tt.wantCode)
+		})
+	}
+
+	if o.balances[alice.login] != 70 || o.held[alice.login] != 70 {
+		t.Errorf("баланс = %v, резерв = %v, ожидалось 70 и 70", o.balances[alice.login], o.held[alice.login])
+	}
+}
+
+func TestWithdrawLimits(t *testing.T) {
+	o := newFakeOrders(map[string]float32{alice.login: 500})
+	o.dailyLimit = 100
+	h := newTestHandler(o, nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantRule   string
+		wantLimit  float64
+	}{
+		{
+			name:       "нулевая сумма",
+			body:       `{"order":"2377225624","sum":0}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrorCodeWithdrawalRule,
+			wantRule:   orders.WithdrawalRulePositiveAmount,
+		},
+		{
+			name:       "списание в пределах лимита",
+			body:       `{"order":"2377225624","sum":60}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "превышение дневного лимита",
+			body:       `{"order":"79927398713","sum":60}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   ErrorCodeWithdrawalRule,
+			wantRule:   orders.WithdrawalRuleDailyLimit,
+			wantLimit:  100,
+		},
+		{
+			name:       "остаток дневного лимита",
+			body:       `{"order":"79927398713","sum":40}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := doRequest(h, http.MethodPost, "/api/user/balance/withdraw", &alice, tt.body, nil)
+			checkResponse(t, response, tt.wantStatus, tt.wantCode)
+
+			if tt.wantRule == "" {
+				return
+			}
+
+			var body ErrorResponseBody
+			err := json.Unmarshal(response.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("не удалось разобрать тело ответа с ошибкой: %v", err)
+			}
+
+			if body.Details["rule"] != tt.wantRule {
+				t.Errorf("нарушенное правило = %v, ожидалось %v", body.Details["rule"], tt.wantRule)
+			}
+
+			if tt.wantLimit != 0 && body.Details["limit"] != tt.wantLimit {
+				t.Errorf("лимит = %v, ожидалось %v", body.Details["limit"], tt.wantLimit)
+			}
+		})
+	}
+
+	if o.balances[alice.login] != 400 {
+		t.Errorf("баланс = %v, ожидалось 400", o.balances[alice.login])
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) getCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaignList, err := h.campaigns.GetCampaigns()
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение списка акций: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, campaignList, http.StatusOK)
+}
+
+func (h *Handler) getCampaign(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор акции:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	campaign, err := h.campaigns.GetCampaign(campaignID)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение акции: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, campaign, http.StatusOK)
+}
+
+func (h *Handler) createCampaign(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := decodeCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	log.Println("Администратор " + currentUserLogin(r) + " создаёт акцию " + campaign.Name)
+
+	err := h.campaigns.CreateCampaign(campaign)
+	if err != nil {
+		log.Println("Ошибка при создании акции: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, campaign, http.StatusCreated)
+}
+
+func (h *Handler) updateCampaign(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор акции:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	campaign, ok := decodeCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	campaign.ID = campaignID
+
+	log.Println("Администратор " + currentUserLogin(r) + " изменяет акцию " + strconv.FormatInt(campaignID, 10))
+
+	err = h.campaigns.UpdateCampaign(campaign)
+	if err != nil {
+		log.Println("Ошибка при изменении акции: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, campaign, http.StatusOK)
+}
+
+func (h *Handler) deleteCampaign(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор акции:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	log.Println("Администратор " + currentUserLogin(r) + " удаляет акцию " + strconv.FormatInt(campaignID, 10))
+
+	err = h.campaigns.DeleteCampaign(campaignID)
+	if err != nil {
+		log.Println("Ошибка при удалении акции: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeCampaign(w http.ResponseWriter, r *http.Request) (*database.Campaign, bool) {
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе акции:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return nil, false
+	}
+
+	campaign := database.Campaign{Active: true}
+	err = json.Unmarshal(request, &campaign)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе акции:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return nil, false
+	}
+
+	return &campaign, true
+}
+
+func writeJSONResponse(w http.ResponseWriter, v any, status int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(v)
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
+	}
+
+	w.WriteHeader(status)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
+}
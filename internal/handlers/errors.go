@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/campaigns"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/i18n"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/idempotency"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/orders"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/rewards"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/vouchers"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/webhooks"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+const (
+	ErrorCodeBadRequest              = "bad_request"
+	ErrorCodeUnsupportedRequest      = "unsupported_request"
+	ErrorCodeUnauthorized            = "unauthorized"
+	ErrorCodeForbidden               = "forbidden"
+	ErrorCodeInvalidMerchantKey      = "invalid_merchant_key"
+	ErrorCodeInternal                = "internal_error"
+	ErrorCodeLoginTaken              = "login_taken"
+	ErrorCodeInvalidCredentials      = "invalid_credentials"
+	ErrorCodeInvalidReferralCode     = "invalid_referral_code"
+	ErrorCodeInvalidOrderNumber      = "invalid_order_number"
+	ErrorCodeOrderOwnedByAnotherUser = "order_owned_by_another_user"
+	ErrorCodeOrderNotFound           = "order_not_found"
+	ErrorCodeOrderStatusConflict     = "order_status_conflict"
+	ErrorCodeInvalidAmount           = "invalid_amount"
+	ErrorCodeInsufficientFunds       = "insufficient_funds"
+	ErrorCodeWithdrawalRule          = "withdrawal_rule_violated"
+	ErrorCodeWithdrawalNotFound      = "withdrawal_not_found"
+	ErrorCodeRefundExceedsWithdrawal = "refund_exceeds_withdrawal"
+	ErrorCodeHoldNotFound            = "hold_not_found"
+	ErrorCodeHoldInactive            = "hold_inactive"
+	ErrorCodeHoldExists              = "hold_exists"
+	ErrorCodeInvalidTransfer         = "invalid_transfer"
+	ErrorCodeInvalidRecipient        = "invalid_recipient"
+	ErrorCodeTransferNotFound        = "transfer_not_found"
+	ErrorCodeRecipientNotFound       = "recipient_not_found"
+	ErrorCodeTransferInactive        = "transfer_inactive"
+	ErrorCodeInvalidTransferPassword = "invalid_transfer_password"
+	ErrorCodeTransferLimitExceeded   = "transfer_limit_exceeded"
+	ErrorCodeCampaignNotFound        = "campaign_not_found"
+	ErrorCodeInvalidCampaign         = "invalid_campaign"
+	ErrorCodeRewardNotFound          = "reward_not_found"
+	ErrorCodeInvalidReward           = "invalid_reward"
+	ErrorCodeRewardUnavailable       = "reward_unavailable"
+	ErrorCodeRedemptionConflict      = "redemption_status_conflict"
+	ErrorCodeInvalidVoucherBatch     = "invalid_voucher_batch"
+	ErrorCodeVoucherNotFound         = "voucher_not_found"
+	ErrorCodeVoucherRedeemed         = "voucher_redeemed"
+	ErrorCodeVoucherExpired          = "voucher_expired"
+	ErrorCodeRateLimited             = "rate_limited"
+	ErrorCodeInvalidIdempotencyKey   = "invalid_idempotency_key"
+	ErrorCodeIdempotencyKeyReused    = "idempotency_key_reused"
+	ErrorCodeIdempotencyInProgress   = "idempotency_request_in_progress"
+	ErrorCodeWebhookNotFound         = "webhook_not_found"
+	ErrorCodeInvalidWebhook          = "invalid_webhook"
+)
+
+type ErrorResponseBody struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+type apiError struct {
+	status  int
+	code    string
+	details map[string]any
+}
+
+func exposeRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := middleware.GetReqID(r.Context())
+		if requestID != "" {
+			w.Header().Set(requestIDHeader, requestID)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, code string, details map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	response, err := json.Marshal(ErrorResponseBody{
+		Code:      code,
+		Message:   i18n.Message(responseLocale(w), code),
+		Details:   details,
+		RequestID: w.Header().Get(requestIDHeader),
+	})
+	if err != nil {
+		log.Println("Ошибка при формировании ответа с ошибкой:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(status)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e := classifyError(err)
+	if e.status == http.StatusInternalServerError {
+		log.Println("Внутренняя ошибка при обработке запроса "+w.Header().Get(requestIDHeader)+":", err)
+	}
+
+	writeErrorResponse(w, e.status, e.code, e.details)
+}
+
+func classifyError(err error) apiError {
+	var orderError *orders.OrderError
+	var statusError *orders.OrderStatusError
+	var ruleError *orders.WithdrawalRuleError
+	var referralError *orders.ReferralError
+	var transferError *orders.TransferError
+	var campaignError *campaigns.CampaignError
+	var rewardError *rewards.RewardError
+	var voucherError *vouchers.VoucherError
+	var keyError *idempotency.KeyError
+	var webhookError *webhooks.WebhookError
+	var userError *database.DBUserError
+	var dbOrderError *database.DBOrderError
+	var dbStatusError *database.DBOrderStatusError
+	var accountError *database.DBAccountError
+	var holdError *database.DBHoldError
+	var refundError *database.DBRefundError
+	var dbTransferError *database.DBTransferError
+	var dbCampaignError *database.DBCampaignError
+	var dbRewardError *database.DBRewardError
+	var dbVoucherError *database.DBVoucherError
+
+	switch {
+	case errors.As(err, &ruleError):
+		return classifyWithdrawalRuleError(ruleError)
+	case errors.As(err, &orderError):
+		return classifyOrderError(orderError)
+	case errors.As(err, &statusError):
+		return apiError{http.StatusConflict, ErrorCodeOrderStatusConflict, map[string]any{"order": statusError.OrderID, "status": statusError.Status, "new_status": statusError.NewStatus}}
+	case errors.As(err, &dbStatusError):
+		return apiError{http.StatusConflict, ErrorCodeOrderStatusConflict, map[string]any{"order": dbStatusError.Order, "new_status": dbStatusError.NewStatus}}
+	case errors.As(err, &referralError) && referralError.NotFound:
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidReferralCode, nil}
+	case errors.As(err, &transferError) && transferError.IncorrectRecipient:
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidRecipient, nil}
+	case errors.As(err, &transferError):
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidTransfer, nil}
+	case errors.As(err, &userError) && userError.Duplicate:
+		return apiError{http.StatusConflict, ErrorCodeLoginTaken, nil}
+	case errors.As(err, &userError):
+		return apiError{http.StatusUnauthorized, ErrorCodeInvalidCredentials, nil}
+	case errors.As(err, &dbOrderError) && dbOrderError.Duplicate:
+		return apiError{http.StatusConflict, ErrorCodeOrderOwnedByAnotherUser, map[string]any{"order": dbOrderError.Order}}
+	case errors.As(err, &accountError) && accountError.InsufficientFunds:
+		return apiError{http.StatusPaymentRequired, ErrorCodeInsufficientFunds, nil}
+	case errors.As(err, &holdError) && holdError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeHoldNotFound, map[string]any{"hold_id": holdError.HoldID}}
+	case errors.As(err, &holdError) && holdError.Inactive:
+		return apiError{http.StatusConflict, ErrorCodeHoldInactive, map[string]any{"hold_id": holdError.HoldID}}
+	case errors.As(err, &holdError) && holdError.Duplicate:
+		return apiError{http.StatusConflict, ErrorCodeHoldExists, nil}
+	case errors.As(err, &refundError) && refundError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeWithdrawalNotFound, map[string]any{"order": refundError.Order}}
+	case errors.As(err, &refundError) && refundError.ExceedsWithdrawal:
+		return apiError{http.StatusUnprocessableEntity, ErrorCodeRefundExceedsWithdrawal, map[string]any{"order": refundError.Order}}
+	case errors.As(err, &dbTransferError):
+		return classifyTransferError(dbTransferError)
+	case errors.As(err, &campaignError) && campaignError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeCampaignNotFound, map[string]any{"campaign_id": campaignError.CampaignID}}
+	case errors.As(err, &campaignError) && campaignError.Invalid:
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidCampaign, map[string]any{"campaign_id": campaignError.CampaignID}}
+	case errors.As(err, &dbCampaignError) && dbCampaignError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeCampaignNotFound, map[string]any{"campaign_id": dbCampaignError.CampaignID}}
+	case errors.As(err, &rewardError) && rewardError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeRewardNotFound, map[string]any{"item_id": rewardError.ItemID}}
+	case errors.As(err, &rewardError) && rewardError.Invalid:
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidReward, map[string]any{"item_id": rewardError.ItemID}}
+	case errors.As(err, &dbRewardError):
+		return classifyRewardError(dbRewardError)
+	case errors.As(err, &voucherError) && voucherError.RateLimited:
+		return apiError{http.StatusTooManyRequests, ErrorCodeRateLimited, nil}
+	case errors.As(err, &voucherError) && voucherError.Invalid:
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidVoucherBatch, nil}
+	case errors.As(err, &dbVoucherError) && dbVoucherError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeVoucherNotFound, nil}
+	case errors.As(err, &dbVoucherError) && dbVoucherError.Redeemed:
+		return apiError{http.StatusConflict, ErrorCodeVoucherRedeemed, nil}
+	case errors.As(err, &dbVoucherError) && dbVoucherError.Expired:
+		return apiError{http.StatusGone, ErrorCodeVoucherExpired, nil}
+	case errors.As(err, &keyError) && keyError.Invalid:
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidIdempotencyKey, nil}
+	case errors.As(err, &keyError) && keyError.Mismatch:
+		return apiError{http.StatusUnprocessableEntity, ErrorCodeIdempotencyKeyReused, map[string]any{"key": keyError.Key}}
+	case errors.As(err, &keyError) && keyError.InProgress:
+		return apiError{http.StatusConflict, ErrorCodeIdempotencyInProgress, map[string]any{"key": keyError.Key}}
+	case errors.As(err, &webhookError) && webhookError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeWebhookNotFound, map[string]any{"webhook_id": webhookError.WebhookID}}
+	case errors.As(err, &webhookError) && webhookError.Invalid:
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidWebhook, nil}
+	default:
+		return apiError{http.StatusInternalServerError, ErrorCodeInternal, nil}
+	}
+}
+
+func classifyOrderError(orderError *orders.OrderError) apiError {
+	var details map[string]any
+	if orderError.OrderID != "" {
+		details = map[string]any{"order": orderError.OrderID}
+	}
+
+	switch {
+	case orderError.IncorrectID:
+		return apiError{http.StatusUnprocessableEntity, ErrorCodeInvalidOrderNumber, details}
+	case orderError.IncorrectAmount:
+		return apiError{http.StatusBadRequest, ErrorCodeInvalidAmount, details}
+	case orderError.InsufficientFunds:
+		return apiError{http.StatusPaymentRequired, ErrorCodeInsufficientFunds, details}
+	case orderError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeOrderNotFound, details}
+	case orderError.Duplicate:
+		return apiError{http.StatusConflict, ErrorCodeOrderOwnedByAnotherUser, details}
+	default:
+		return apiError{http.StatusInternalServerError, ErrorCodeInternal, nil}
+	}
+}
+
+func classifyWithdrawalRuleError(ruleError *orders.WithdrawalRuleError) apiError {
+	status := http.StatusBadRequest
+	if ruleError.Rule == orders.WithdrawalRuleDailyLimit || ruleError.Rule == orders.WithdrawalRuleMonthlyLimit {
+		status = http.StatusForbidden
+	}
+
+	details := map[string]any{
+		"rule":      ruleError.Rule,
+		"requested": ruleError.Requested,
+	}
+
+	if ruleError.Limit != 0 {
+		details["limit"] = ruleError.Limit
+	}
+
+	return apiError{status, ErrorCodeWithdrawalRule, details}
+}
+
+func classifyTransferError(transferError *database.DBTransferError) apiError {
+	var details map[string]any
+	if transferError.TransferID != 0 {
+		details = map[string]any{"transfer_id": transferError.TransferID}
+	}
+
+	switch {
+	case transferError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeTransferNotFound, details}
+	case transferError.RecipientNotFound:
+		return apiError{http.StatusNotFound, ErrorCodeRecipientNotFound, nil}
+	case transferError.Inactive:
+		return apiError{http.StatusConflict, ErrorCodeTransferInactive, details}
+	case transferError.InvalidPassword:
+		return apiError{http.StatusForbidden, ErrorCodeInvalidTransferPassword, details}
+	case transferError.LimitExceeded:
+		return apiError{http.StatusTooManyRequests, ErrorCodeTransferLimitExceeded, nil}
+	default:
+		return apiError{http.StatusInternalServerError, ErrorCodeInternal, nil}
+	}
+}
+
+func classifyRewardError(rewardError *database.DBRewardError) apiError {
+	switch {
+	case rewardError.NotFound:
+		return apiError{http.StatusNotFound, ErrorCodeRewardNotFound, nil}
+	case rewardError.OutOfStock:
+		return apiError{http.StatusConflict, ErrorCodeRewardUnavailable, map[string]any{"reason": "out_of_stock", "item_id": rewardError.ItemID}}
+	case rewardError.Inactive:
+		return apiError{http.StatusConflict, ErrorCodeRedemptionConflict, map[string]any{"redemption_id": rewardError.RedemptionID}}
+	case rewardError.Unavailable:
+		return apiError{http.StatusConflict, ErrorCodeRewardUnavailable, map[string]any{"reason": "unavailable", "item_id": rewardError.ItemID}}
+	default:
+		return apiError{http.StatusInternalServerError, ErrorCodeInternal, nil}
+	}
+}
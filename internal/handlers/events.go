@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	lastEventIDHeader    = "Last-Event-ID"
+	eventStreamRetry     = 3000
+	eventStreamHeartbeat = 30 * time.Second
+	eventStreamMediaType = "text/event-stream"
+	eventStreamPing      = ": ping\n\n"
+)
+
+func (h *Handler) getEvents(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	user := currentUserLogin(r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Println("Потоковая передача событий не поддерживается для данного соединения")
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
+	}
+
+	value := r.Header.Get(lastEventIDHeader)
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+
+	var lastEventID int64
+	if value != "" {
+		var err error
+		lastEventID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || lastEventID < 0 {
+			log.Println("Неверный идентификатор последнего события '" + value + "'")
+			writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, map[string]any{"parameter": "last_event_id"})
+			return
+		}
+	}
+
+	subscription := h.events.Subscribe(user)
+	defer subscription.Cancel()
+
+	backlog := make([]database.Event, 0)
+	if value != "" {
+		var err error
+		backlog, err = h.events.GetEventsAfter(user, lastEventID)
+		if err != nil {
+			log.Println("Ошибка при получении пропущенных событий пользователя: " + err.Error())
+			writeError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", eventStreamMediaType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry)
+	if err != nil {
+		log.Println("Ошибка при записи события в поток:", err)
+		return
+	}
+
+	for _, event := range backlog {
+		err = writeEvent(w, &event)
+		if err != nil {
+			log.Println("Ошибка при записи события в поток:", err)
+			return
+		}
+
+		lastEventID = event.ID
+	}
+
+	flusher.Flush()
+	log.Printf("Пользователь '%v' подписан на поток событий, отправлено пропущенных событий: %v\n", user, len(backlog))
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Println("Пользователь '" + user + "' отключился от потока событий")
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				log.Println("Поток событий пользователя '" + user + "' закрыт")
+				return
+			}
+
+			if event.ID <= lastEventID {
+				continue
+			}
+
+			err = writeEvent(w, &event)
+			lastEventID = event.ID
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, eventStreamPing)
+		}
+
+		if err != nil {
+			log.Println("Ошибка при записи события в поток:", err)
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event *database.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Payload)
+	return err
+}
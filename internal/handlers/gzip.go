@@ -17,6 +17,16 @@ func (w gzipWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+func (w gzipWriter) Flush() {
+	if gz, ok := w.Writer.(*gzip.Writer); ok {
+		gz.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func gzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
@@ -25,10 +35,16 @@ func gzipHandler(next http.Handler) http.Handler {
 			return
 		}
 
+		if strings.Contains(r.Header.Get("Accept"), eventStreamMediaType) {
+			log.Println("Поток событий передаётся без сжатия gzip")
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		gz, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
 		if err != nil {
 			log.Println("Ошибка при формировании ответа в gzip:", err)
-			http.Error(w, "ошибка при формировании ответа в gzip: "+err.Error(), http.StatusInternalServerError)
+			writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
 			return
 		}
 		defer gz.Close()
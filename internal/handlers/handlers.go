@@ -2,42 +2,152 @@ package handlers
 
 import (
 	"log"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/auth"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/campaigns"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/events"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/idempotency"
 	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/orders"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/rewards"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/vouchers"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/webhooks"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Handler struct {
 	*chi.Mux
-	authenticator    auth.Authenticator
-	orders           orders.OrderAdderGetter
-	currentUserLogin string
-	baseURL          string
+	authenticator auth.Authenticator
+	orders        orders.OrderAdderGetter
+	campaigns     campaigns.CampaignManager
+	rewards       rewards.RewardManager
+	vouchers      vouchers.VoucherManager
+	idempotency   idempotency.KeyManager
+	events        events.Bus
+	webhooks      webhooks.WebhookManager
+	baseURL       string
+	admins        map[string]struct{}
+	merchants     map[string]string
+	proxies       []*net.IPNet
 }
 
-func NewHandler(baseURL string, a auth.Authenticator, o orders.OrderAdderGetter) *Handler {
+func NewHandler(baseURL string, a auth.Authenticator, o orders.OrderAdderGetter, c campaigns.CampaignManager, rw rewards.RewardManager, v vouchers.VoucherManager, i idempotency.KeyManager, e events.Bus, wh webhooks.WebhookManager, adminLogins, merchantKeys, trustedProxies []string) *Handler {
 	log.Println("Base URL:", baseURL)
 
 	handler := &Handler{
 		Mux:           chi.NewMux(),
 		authenticator: a,
 		orders:        o,
+		campaigns:     c,
+		rewards:       rw,
+		vouchers:      v,
+		idempotency:   i,
+		events:        e,
+		webhooks:      wh,
 		baseURL:       baseURL,
+		admins:        make(map[string]struct{}),
+		merchants:     make(map[string]string),
+	}
+
+	for _, login := range adminLogins {
+		if login != "" {
+			handler.admins[login] = struct{}{}
+		}
+	}
+
+	for _, merchantKey := range merchantKeys {
+		merchant, key, found := strings.Cut(merchantKey, ":")
+		if !found || merchant == "" || key == "" {
+			log.Println("Ключ интеграции магазина пропущен: ожидается формат merchant:key")
+			continue
+		}
+
+		handler.merchants[merchant] = key
+	}
+
+	for _, proxy := range trustedProxies {
+		network, err := parseNetwork(strings.TrimSpace(proxy))
+		if err != nil {
+			log.Println("Адрес доверенного прокси пропущен:", err)
+			continue
+		}
+
+		handler.proxies = append(handler.proxies, network)
 	}
 
 	handler.Route("/", func(r chi.Router) {
+		handler.Use(middleware.RequestID)
+		handler.Use(exposeRequestID)
+		handler.Use(negotiateLocale)
 		handler.Use(handler.authenticate)
 		handler.Use(gzipHandler)
 
 		r.Post("/api/user/register", handler.registerUser)
 		r.Post("/api/user/login", handler.loginUser)
-		r.Post("/api/user/orders", handler.addOrder)
+		r.Post("/api/user/orders", handler.idempotent(handler.addOrder))
 		r.Get("/api/user/orders", handler.getOrders)
+		r.Post("/api/user/orders/batch", handler.addOrders)
+		r.Post("/api/user/orders/status", handler.getOrderStatuses)
+		r.Get("/api/user/orders/{number}", handler.getOrder)
+		r.Get("/api/user/orders/{number}/history", handler.getOrderHistory)
 		r.Get("/api/user/balance", handler.getBalance)
-		r.Post("/api/user/balance/withdraw", handler.withdrawPoints)
+		r.With(handler.identifyMerchant).Post("/api/user/balance/withdraw", handler.idempotent(handler.withdrawPoints))
+		r.Post("/api/user/balance/transfer", handler.transferPoints)
+		r.Post("/api/user/balance/transfer/{id}/confirm", handler.confirmTransfer)
+		r.Get("/api/user/balance/transfers", handler.getTransfers)
+		r.Post("/api/user/balance/holds", handler.createHold)
+		r.Get("/api/user/balance/holds", handler.getHolds)
+		r.Post("/api/user/balance/holds/{id}/capture", handler.captureHold)
+		r.Post("/api/user/balance/holds/{id}/void", handler.voidHold)
 		r.Get("/api/user/withdrawals", handler.getWithdrawals)
+		r.Get("/api/user/transactions", handler.getTransactions)
+		r.Get("/api/user/statement", handler.getStatement)
+		r.Get("/api/user/statement/export", handler.exportStatement)
+		r.Get("/api/user/events", handler.getEvents)
+		r.Get("/api/user/webhooks", handler.getWebhooks)
+		r.Post("/api/user/webhooks", handler.createWebhook)
+		r.Get("/api/user/webhooks/{id}", handler.getWebhook)
+		r.Put("/api/user/webhooks/{id}", handler.updateWebhook)
+		r.Delete("/api/user/webhooks/{id}", handler.deleteWebhook)
+		r.Get("/api/user/webhooks/{id}/deliveries", handler.getWebhookDeliveries)
+		r.Get("/api/user/tier", handler.getTier)
+		r.Get("/api/user/referral", handler.getReferral)
+		r.Post("/api/user/redemptions", handler.createRedemption)
+		r.Get("/api/user/redemptions", handler.getRedemptions)
+		r.Get("/api/rewards", handler.getRewards)
+		r.Post("/api/user/vouchers/redeem", handler.redeemVoucher)
+
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Use(handler.authorizeAdmin)
+
+			r.Post("/orders/{number}/status", handler.changeOrderStatus)
+			r.Get("/orders/{number}/history", handler.getAdminOrderHistory)
+			r.Post("/orders/{number}/recheck", handler.recheckOrder)
+			r.Post("/withdrawals/{number}/refund", handler.refundWithdrawal)
+			r.Get("/campaigns", handler.getCampaigns)
+			r.Post("/campaigns", handler.createCampaign)
+			r.Get("/campaigns/{id}", handler.getCampaign)
+			r.Put("/campaigns/{id}", handler.updateCampaign)
+			r.Delete("/campaigns/{id}", handler.deleteCampaign)
+			r.Get("/rewards", handler.getAdminRewards)
+			r.Post("/rewards", handler.createReward)
+			r.Get("/rewards/{id}", handler.getReward)
+			r.Put("/rewards/{id}", handler.updateReward)
+			r.Delete("/rewards/{id}", handler.deleteReward)
+			r.Post("/redemptions/{id}/status", handler.changeRedemptionStatus)
+			r.Get("/vouchers/batches", handler.getVoucherBatches)
+			r.Post("/vouchers/batches", handler.createVoucherBatch)
+		})
+
+		r.Route("/api/merchant", func(r chi.Router) {
+			r.Use(handler.authorizeMerchant)
+
+			r.Post("/withdrawals/{number}/refund", handler.refundWithdrawal)
+		})
+
 		r.MethodNotAllowed(handler.badRequest)
 	})
 
@@ -45,5 +155,6 @@ func NewHandler(baseURL string, a auth.Authenticator, o orders.OrderAdderGetter)
 }
 
 func (h *Handler) badRequest(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "неподдерживаемый запрос: '"+r.RequestURI+"'", http.StatusBadRequest)
+	log.Println("Неподдерживаемый запрос: '" + r.Method + " " + r.RequestURI + "'")
+	writeErrorResponse(w, http.StatusBadRequest, ErrorCodeUnsupportedRequest, map[string]any{"method": r.Method, "path": r.URL.Path})
 }
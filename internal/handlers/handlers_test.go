@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/auth"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/idempotency"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/orders"
+)
+
+type testUser struct {
+	login    string
+	password string
+	token    string
+}
+
+var (
+	alice = testUser{login: "alice", password: "alice-password", token: "alice-token"}
+	bob   = testUser{login: "bob", password: "bob-password", token: "bob-token"}
+
+	errTestUnauthorized = errors.New("пользователь не найден")
+)
+
+type fakeAuth struct {
+	auth.Authenticator
+	users []testUser
+}
+
+func (a *fakeAuth) Authenticate(token string) (string, error) {
+	for _, u := range a.users {
+		if u.token == token {
+			return u.login, nil
+		}
+	}
+
+	return "", errTestUnauthorized
+}
+
+func (a *fakeAuth) VerifyPassword(login, password string) (bool, error) {
+	for _, u := range a.users {
+		if u.login == login {
+			return u.password == password, nil
+		}
+	}
+
+	return false, errTestUnauthorized
+}
+
+type fakeOrders struct {
+	orders.OrderAdderGetter
+
+	lock        sync.Mutex
+	balances    map[string]float32
+	held        map[string]float32
+	dailyLimit  float32
+	withdrawals []fakeWithdrawal
+	transfers   []database.Transfer
+}
+
+type fakeWithdrawal struct {
+	user     string
+	order    string
+	merchant string
+	amount   float32
+}
+
+func newFakeOrders(balances map[string]float32) *fakeOrders {
+	return &fakeOrders{balances: balances, held: make(map[string]float32)}
+}
+
+func newTestHandler(o orders.OrderAdderGetter, k idempotency.KeyManager) *Handler {
+	return NewHandler("", &fakeAuth{users: []testUser{alice, bob}}, o, nil, nil, nil, k, nil, nil, nil, []string{"shop:shop-key", "other:other-key"}, nil)
+}
+
+func doRequest(h http.Handler, method, path string, user *testUser, body string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if user != nil {
+		request.Header.Set("Authorization", user.token)
+	}
+
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, request)
+
+	return response
+}
+
+func checkResponse(t *testing.T, response *httptest.ResponseRecorder, wantStatus int, wantCode string) {
+	t.Helper()
+
+	if response.Code != wantStatus {
+		t.Fatalf("код ответа = %v, ожидалось %v, тело ответа: %v", response.Code, wantStatus, response.Body.String())
+	}
+
+	if wantCode == "" {
+		return
+	}
+
+	var body ErrorResponseBody
+	err := json.Unmarshal(response.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatalf("не удалось разобрать тело ответа с ошибкой: %v", err)
+	}
+
+	if body.Code != wantCode {
+		t.Errorf("код ошибки = %v, ожидалось %v", body.Code, wantCode)
+	}
+}
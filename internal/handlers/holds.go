@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/i18n"
+	"github.com/go-chi/chi/v5"
+)
+
+type HoldRequestBody struct {
+	OrderID    string  `json:"order"`
+	Amount     float32 `json:"sum"`
+	OrderTotal float32 `json:"order_total,omitempty"`
+	TTL        int64   `json:"ttl"`
+}
+
+func (h *Handler) createHold(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе резервирования баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	requestBody := HoldRequestBody{}
+	err = json.Unmarshal(request, &requestBody)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе резервирования баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	log.Println("Переданные данные для резервирования баллов:", requestBody)
+
+	hold, err := h.orders.CreateHold(currentUserLogin(r), requestBody.OrderID, requestBody.Amount, requestBody.OrderTotal, time.Duration(requestBody.TTL)*time.Second)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на резервирование баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	hold.StatusLabel = i18n.StatusLabel(responseLocale(w), hold.Status)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(hold)
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
+}
+
+func (h *Handler) getHolds(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	holds, err := h.orders.GetHolds(currentUserLogin(r))
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение резервов баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	if len(holds) == 0 {
+		log.Println("Для пользователя " + currentUserLogin(r) + " не найдено активных резервов баллов")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	locale := responseLocale(w)
+	for i := range holds {
+		holds[i].StatusLabel = i18n.StatusLabel(locale, holds[i].Status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(holds)
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
+}
+
+func (h *Handler) captureHold(w http.ResponseWriter, r *http.Request) {
+	h.finishHold(w, r, h.orders.CaptureHold)
+}
+
+func (h *Handler) voidHold(w http.ResponseWriter, r *http.Request) {
+	h.finishHold(w, r, h.orders.VoidHold)
+}
+
+func (h *Handler) finishHold(w http.ResponseWriter, r *http.Request, finish func(string, int64) error) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	holdID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор резерва баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	err = finish(currentUserLogin(r), holdID)
+	if err != nil {
+		log.Println("Ошибка при завершении резерва баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/idempotency"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (h *Handler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		user := currentUserLogin(r)
+		if key == "" || user == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Println("Ошибка при чтении тела запроса с ключом идемпотентности:", err)
+			writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		stored, token, err := h.idempotency.Begin(user, key, r.Method, r.URL.Path, body)
+		if err != nil {
+			log.Println("Ошибка при обработке ключа идемпотентности: " + err.Error())
+			writeError(w, err)
+			return
+		}
+
+		if stored != nil {
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+
+			w.Header().Set(idempotencyReplayedHeader, "true")
+			w.WriteHeader(stored.StatusCode)
+
+			_, err = w.Write(stored.Body)
+			if err != nil {
+				log.Println("Ошибка при записи ответа в тело запроса:", err)
+			}
+
+			return
+		}
+
+		stop := h.idempotency.KeepAlive(user, key, token)
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r)
+		stop()
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		if recorder.status >= http.StatusInternalServerError {
+			err = h.idempotency.Abort(user, key, token)
+			if err != nil {
+				log.Println("Ошибка при освобождении ключа идемпотентности '"+key+"':", err)
+			}
+
+			return
+		}
+
+		err = h.idempotency.Complete(user, key, token, &idempotency.Response{
+			StatusCode:  recorder.status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			log.Println("Ошибка при сохранении ответа по ключу идемпотентности '"+key+"':", err)
+		}
+	}
+}
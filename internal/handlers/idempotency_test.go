@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/idempotency"
+)
+
+type fakeKey struct {
+	method   string
+	path     string
+	body     []byte
+	token    string
+	response *idempotency.Response
+}
+
+type fakeKeys struct {
+	lock   sync.Mutex
+	keys   map[string]*fakeKey
+	tokens int
+}
+
+func newFakeKeys() *fakeKeys {
+	return &fakeKeys{keys: make(map[string]*fakeKey)}
+}
+
+func (k *fakeKeys) Begin(user, key, method, path string, body []byte) (*idempotency.Response, string, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	existing, found := k.keys[user+"/"+key]
+	if !found {
+		k.tokens++
+		token := strconv.Itoa(k.tokens)
+		k.keys[user+"/"+key] = &fakeKey{method: method, path: path, body: body, token: token}
+		return nil, token, nil
+	}
+
+	if existing.method != method || existing.path != path || !bytes.Equal(existing.body, body) {
+		return nil, "", idempotency.NewKeyError(key, false, true, false, errors.New("ключ идемпотентности уже использован для другого запроса"))
+	}
+
+	if existing.response == nil {
+		return nil, "", idempotency.NewKeyError(key, false, false, true, errors.New("запрос с ключом идемпотентности ещё обрабатывается"))
+	}
+
+	return existing.response, "", nil
+}
+
+func (k *fakeKeys) KeepAlive(user, key, token string) func() {
+	return func() {}
+}
+
+func (k *fakeKeys) Complete(user, key, token string, response *idempotency.Response) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	existing, found := k.keys[user+"/"+key]
+	if !found || existing.token != token {
+		return errors.New("ключ идемпотентности больше не удерживается этим запросом")
+	}
+
+	existing.response = response
+	return nil
+}
+
+func (k *fakeKeys) Abort(user, key, token string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	existing, found := k.keys[user+"/"+key]
+	if !found || existing.token != token {
+		return errors.New("ключ идемпотентности больше не удерживается этим запросом")
+	}
+
+	delete(k.keys, user+"/"+key)
+	return nil
+}
+
+func (k *fakeKeys) Close() {
+}
+
+type failingOrders struct {
+	*fakeOrders
+	failures int
+}
+
+func (o *failingOrders) WithdrawForOrder(user, orderID, merchant string, amount, orderTotal float32) error {
+	if o.failures > 0 {
+		o.failures--
+		return errors.New("соединение с БД потеряно")
+	}
+
+	return o.fakeOrders.WithdrawForOrder(user, orderID, merchant, amount, orderTotal)
+}
+
+func TestIdempotentWithdraw(t *testing.T) {
+	o := &failingOrders{fakeOrders: newFakeOrders(map[string]float32{alice.login: 100, bob.login: 100}), failures: 1}
+	keys := newFakeKeys()
+	h := newTestHandler(o, keys)
+
+	_, _, err := keys.Begin(alice.login, "in-progress", http.MethodPost, "/api/user/balance/withdraw", []byte(`{"order":"2377225624","sum":10}`))
+	if err != nil {
+		t.Fatalf("не удалось зарезервировать ключ идемпотентности: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		user         *testUser
+		key          string
+		body         string
+		wantStatus   int
+		wantCode     string
+		wantReplayed bool
+	}{
+		{
+			name:       "ошибка сервера не сохраняется",
+			user:       &alice,
+			key:        "retry",
+			body:       `{"order":"2377225624","sum":10}`,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   ErrorCodeInternal,
+		},
+		{
+			name:       "повтор после ошибки сервера выполняется заново",
+			user:       &alice,
+			key:        "retry",
+			body:       `{"order":"2377225624","sum":10}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "повтор успешного запроса",
+			user:         &alice,
+			key:          "retry",
+			body:         `{"order":"2377225624","sum":10}`,
+			wantStatus:   http.StatusOK,
+			wantReplayed: true,
+		},
+		{
+			name:       "ключ использован для другого запроса",
+			user:       &alice,
+			key:        "retry",
+			body:       `{"order":"2377225624","sum":20}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   ErrorCodeIdempotencyKeyReused,
+		},
+		{
+			name:       "запрос ещё обрабатывается",
+			user:       &alice,
+			key:        "in-progress",
+			body:       `{"order":"2377225624","sum":10}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   ErrorCodeIdempotencyInProgress,
+		},
+		{
+			name:       "ответ с ошибкой клиента сохраняется",
+			user:       &alice,
+			key:        "insufficient",
+			body:       `{"order":"79927398713","sum":500}`,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   ErrorCodeInsufficientFunds,
+		},
+		{
+			name:         "повтор запроса с ошибкой клиента",
+			user:         &alice,
+			key:          "insufficient",
+			body:         `{"order":"79927398713","sum":500}`,
+			wantStatus:   http.StatusPaymentRequired,
+			wantCode:     ErrorCodeInsufficientFunds,
+			wantReplayed: true,
+		},
+		{
+			name:       "ключи разных пользователей не пересекаются",
+			user:       &bob,
+			key:        "retry",
+			body:       `{"order":"2377225624","sum":10}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := doRequest(h, http.MethodPost, "/api/user/balance/withdraw", tt.user, tt.body, map[string]string{idempotencyKeyHeader: tt.key})
+			checkResponse(t, response, tt.wantStatus, tt.wantCode)
+
+			replayed := response.Header().Get(idempotencyReplayedHeader) == "true"
+			if replayed != tt.wantReplayed {
+				t.Errorf("ответ повторён = %v, ожидалось %v", replayed, tt.wantReplayed)
+			}
+		})
+	}
+
+	if o.balances[alice.login] != 90 || o.balances[bob.login] != 90 {
+		t.Errorf("балансы = %v, ожидалось alice: 90, bob: 90", o.balances)
+	}
+}
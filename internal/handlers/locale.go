@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/i18n"
+)
+
+func negotiateLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", i18n.Negotiate(r.Header.Get("Accept-Language")))
+		w.Header().Add("Vary", "Accept-Language")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func responseLocale(w http.ResponseWriter) string {
+	locale := w.Header().Get("Content-Language")
+	if locale == "" {
+		return i18n.DefaultLocale
+	}
+
+	return locale
+}
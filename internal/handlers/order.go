@@ -1,44 +1,49 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/i18n"
 	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/orders"
+	"github.com/go-chi/chi/v5"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strings"
 )
 
 func (h *Handler) addOrder(w http.ResponseWriter, r *http.Request) {
-	if h.currentUserLogin == "" {
+	if currentUserLogin(r) == "" {
 		log.Println("Пользователь не аутентифицирован")
-		http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
 		return
 	}
 
 	request, err := decodeRequest(r)
 	if err != nil {
 		log.Println("Неверный формат данных в запросе добавления заказа:", err)
-		http.Error(w, "неверный формат данных в запросе добавления заказа: "+err.Error(), http.StatusBadRequest)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
 		return
 	}
 
 	orderID := string(request)
 
 	var orderError *orders.OrderError
-	err = h.orders.AddOrder(h.currentUserLogin, orderID)
-
-	if err != nil && errors.As(err, &orderError) {
-		switch {
-		case orderError.IncorrectID:
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		case orderError.Duplicate && orderError.User == h.currentUserLogin:
-			http.Error(w, err.Error(), http.StatusOK)
-		case orderError.Duplicate && orderError.User != h.currentUserLogin:
-			http.Error(w, err.Error(), http.StatusConflict)
-		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	err = h.orders.AddOrder(currentUserLogin(r), orderID)
 
+	if err != nil && errors.As(err, &orderError) && orderError.Duplicate && orderError.User == currentUserLogin(r) {
+		log.Println("Номер заказа '" + orderID + "' уже был загружен пользователем")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на добавление заказа: " + err.Error())
+		writeError(w, err)
 		return
 	}
 
@@ -46,32 +51,292 @@ func (h *Handler) addOrder(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+type OrderUploadResponseBody struct {
+	Orders []database.OrderUploadResult `json:"orders"`
+}
+
+func (h *Handler) addOrders(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе пакетной загрузки заказов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	orderIDs, err := parseOrderNumbers(r.Header.Get("Content-Type"), request)
+	if err != nil || len(orderIDs) == 0 {
+		log.Println("Неверный формат списка номеров заказов в запросе пакетной загрузки:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	if len(orderIDs) > orders.MaxOrderUploadBatchSize {
+		log.Printf("Превышено допустимое количество номеров заказов в пакетной загрузке: %v\n", len(orderIDs))
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, map[string]any{"limit": orders.MaxOrderUploadBatchSize})
+		return
+	}
+
+	results, err := h.orders.AddOrders(currentUserLogin(r), orderIDs)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на пакетную загрузку заказов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	log.Printf("Пакетная загрузка заказов пользователя '%v' обработана, номеров: %v\n", currentUserLogin(r), len(results))
+	writeJSONResponse(w, OrderUploadResponseBody{Orders: results}, http.StatusOK)
+}
+
+func parseOrderNumbers(contentType string, request []byte) ([]string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var orderIDs []string
+
+	switch mediaType {
+	case "application/json":
+		err = json.Unmarshal(request, &orderIDs)
+		if err != nil {
+			return nil, err
+		}
+	case "text/csv":
+		reader := csv.NewReader(bytes.NewReader(request))
+		reader.FieldsPerRecord = -1
+
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			for _, field := range record {
+				field = strings.TrimSpace(field)
+				if field != "" {
+					orderIDs = append(orderIDs, field)
+				}
+			}
+		}
+	default:
+		return nil, errors.New("неподдерживаемый тип содержимого " + mediaType)
+	}
+
+	return orderIDs, nil
+}
+
 func (h *Handler) getOrders(w http.ResponseWriter, r *http.Request) {
-	if h.currentUserLogin == "" {
+	if currentUserLogin(r) == "" {
 		log.Println("Пользователь не аутентифицирован")
-		http.Error(w, "пользователь не аутентифицирован", http.StatusUnauthorized)
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
 		return
 	}
 
-	orders, err := h.orders.GetOrders(h.currentUserLogin)
+	filter, parameter, err := parseOrderFilter(r)
+	if err != nil {
+		log.Println("Неверный параметр '"+parameter+"' в запросе списка заказов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, map[string]any{"parameter": parameter})
+		return
+	}
+
+	page, err := h.orders.GetOrders(currentUserLogin(r), filter)
 	if err != nil {
 		log.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
+	orders := page.Orders
+	if page.Next != nil {
+		h.writeNextPageHeaders(w, r, page.Next.String())
+	}
+
 	if len(orders) == 0 {
 		log.Println("Заказы для пользователя не найдены")
-		http.Error(w, "заказы для пользователя не найдены", http.StatusNoContent)
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	locale := responseLocale(w)
+	for i := range orders {
+		orders[i].StatusLabel = i18n.StatusLabel(locale, orders[i].Status)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
 	response, err := json.Marshal(orders)
 	if err != nil {
 		log.Println("Ошибка при формировании ответа:", err)
-		http.Error(w, "ошибка при формировании ответа: "+err.Error(), http.StatusInternalServerError)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
+}
+
+func parseOrderFilter(r *http.Request) (*database.OrderFilter, string, error) {
+	query := r.URL.Query()
+	filter := database.OrderFilter{}
+
+	for _, status := range parseListParameter(query, "status") {
+		switch status {
+		case orders.OrderStatusNew, orders.OrderStatusProcessing, orders.OrderStatusProcessed, orders.OrderStatusInvalid:
+			filter.Statuses = append(filter.Statuses, status)
+		default:
+			return nil, "status", errors.New("неизвестный статус заказа " + status)
+		}
+	}
+
+	from, to, parameter, err := parsePeriod(query)
+	if err != nil {
+		return nil, parameter, err
+	}
+
+	filter.From, filter.To = from, to
+
+	switch query.Get("sort") {
+	case "", "uploaded_at":
+		filter.Descending = false
+	case "-uploaded_at":
+		filter.Descending = true
+	default:
+		return nil, "sort", errors.New("неподдерживаемый порядок сортировки " + query.Get("sort"))
+	}
+
+	filter.Limit, err = parseLimit(query)
+	if err != nil {
+		return nil, "limit", err
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		filter.After, err = database.ParseOrderCursor(value)
+		if err != nil {
+			return nil, "cursor", err
+		}
+	}
+
+	return &filter, "", nil
+}
+
+func (h *Handler) getOrder(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	orderID := chi.URLParam(r, "number")
+	order, err := h.orders.GetOrder(currentUserLogin(r), orderID)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение заказа " + orderID + ": " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	order.StatusLabel = i18n.StatusLabel(responseLocale(w), order.Status)
+
+	writeJSONResponse(w, order, http.StatusOK)
+}
+
+type OrderStatusesResponseBody struct {
+	Orders   []database.OrderWithAccrual `json:"orders"`
+	NotFound []string                    `json:"not_found,omitempty"`
+}
+
+func (h *Handler) getOrderStatuses(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе статусов заказов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	var orderIDs []string
+	err = json.Unmarshal(request, &orderIDs)
+	if err != nil || len(orderIDs) == 0 {
+		log.Println("Неверный формат списка номеров заказов в запросе статусов заказов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	if len(orderIDs) > orders.MaxOrderStatusBatchSize {
+		log.Printf("Превышено допустимое количество номеров заказов в запросе статусов: %v\n", len(orderIDs))
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, map[string]any{"limit": orders.MaxOrderStatusBatchSize})
+		return
+	}
+
+	found, notFound, err := h.orders.GetOrderStatuses(currentUserLogin(r), orderIDs)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса статусов заказов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	locale := responseLocale(w)
+	for i := range found {
+		found[i].StatusLabel = i18n.StatusLabel(locale, found[i].Status)
+	}
+
+	writeJSONResponse(w, OrderStatusesResponseBody{Orders: found, NotFound: notFound}, http.StatusOK)
+}
+
+func (h *Handler) getOrderHistory(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	orderID := chi.URLParam(r, "number")
+	history, err := h.orders.GetUserOrderHistory(currentUserLogin(r), orderID)
+	writeOrderHistory(w, orderID, history, err)
+}
+
+func writeOrderHistory(w http.ResponseWriter, orderID string, history []database.OrderStatusChange, err error) {
+	if err != nil {
+		log.Println(err)
+		writeError(w, err)
+		return
+	}
+
+	if len(history) == 0 {
+		log.Println("История статусов заказа " + orderID + " не найдена")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	locale := responseLocale(w)
+	for i := range history {
+		history[i].StatusLabel = i18n.StatusLabel(locale, history[i].NewStatus)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(history)
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
 		return
 	}
 
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (h *Handler) writeNextPageHeaders(w http.ResponseWriter, r *http.Request, cursor string) {
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+
+	w.Header().Set("X-Next-Cursor", cursor)
+	w.Header().Set("Link", "<"+strings.TrimSuffix(h.baseURL, "/")+r.URL.Path+"?"+query.Encode()+">; rel=\"next\"")
+}
+
+func parseListParameter(query url.Values, name string) []string {
+	result := make([]string, 0)
+
+	for _, value := range query[name] {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.ToUpper(strings.TrimSpace(item))
+			if item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+
+	return result
+}
+
+func parseLimit(query url.Values) (int, error) {
+	value := query.Get("limit")
+	if value == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("размер страницы должен быть положительным числом")
+	}
+
+	return limit, nil
+}
+
+func parsePeriod(query url.Values) (*time.Time, *time.Time, string, error) {
+	var from, to *time.Time
+	var err error
+
+	if value := query.Get("from"); value != "" {
+		from, err = parseDate(value, false)
+		if err != nil {
+			return nil, nil, "from", err
+		}
+	}
+
+	if value := query.Get("to"); value != "" {
+		to, err = parseDate(value, true)
+		if err != nil {
+			return nil, nil, "to", err
+		}
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, "to", errors.New("конец периода должен быть позже его начала")
+	}
+
+	return from, to, "", nil
+}
+
+func parseDate(value string, endOfPeriod bool) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return &t, nil
+	}
+
+	t, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.New("дата '" + value + "' должна быть в формате RFC3339 или ГГГГ-ММ-ДД")
+	}
+
+	if endOfPeriod {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return &t, nil
+}
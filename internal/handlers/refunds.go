@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const merchantKeyHeader = "X-Merchant-Key"
+
+type RefundRequestBody struct {
+	Amount float32 `json:"sum"`
+}
+
+const merchantIDKey contextKey = "merchant"
+
+func (h *Handler) merchantByKey(key string) string {
+	merchant := ""
+	for id, merchantKey := range h.merchants {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(merchantKey)) == 1 {
+			merchant = id
+		}
+	}
+
+	return merchant
+}
+
+func (h *Handler) authorizeMerchant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(merchantKeyHeader)
+
+		merchant := h.merchantByKey(key)
+		if key == "" || merchant == "" {
+			log.Println("Запрос от интеграции магазина не авторизован")
+			writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeInvalidMerchantKey, nil)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), merchantIDKey, merchant)))
+	})
+}
+
+func (h *Handler) identifyMerchant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(merchantKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		merchant := h.merchantByKey(key)
+		if merchant == "" {
+			log.Println("Неверный ключ интеграции магазина в запросе пользователя")
+			writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeInvalidMerchantKey, nil)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), merchantIDKey, merchant)))
+	})
+}
+
+func currentMerchant(r *http.Request) string {
+	merchant, _ := r.Context().Value(merchantIDKey).(string)
+	return merchant
+}
+
+func (h *Handler) refundWithdrawal(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "number")
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе возврата баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	requestBody := RefundRequestBody{}
+	if len(request) > 0 {
+		err = json.Unmarshal(request, &requestBody)
+		if err != nil {
+			log.Println("Неверный формат данных в запросе возврата баллов:", err)
+			writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+			return
+		}
+	}
+
+	merchant := currentMerchant(r)
+	log.Println("Запрошен возврат баллов по заказу "+orderID+", магазин '"+merchant+"', сумма:", requestBody.Amount)
+
+	refund, err := h.orders.RefundWithdrawal(orderID, merchant, requestBody.Amount)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на возврат баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(&TransactionResponseBody{
+		OrderNumber: refund.OrderNumber,
+		Type:        refund.Type,
+		Amount:      refund.Amount,
+		CreatedAt:   refund.CreatedAt,
+	})
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+func (o *fakeOrders) RefundWithdrawal(orderID, merchant string, amount float32) (*database.Transaction, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for i := range o.withdrawals {
+		withdrawal := &o.withdrawals[i]
+		if withdrawal.order != orderID || withdrawal.merchant != merchant {
+			continue
+		}
+
+		if amount == 0 {
+			amount = withdrawal.amount
+		}
+
+		if amount > withdrawal.amount {
+			return nil, database.NewDBRefundError(orderID, false, true, errors.New("сумма возврата превышает сумму списания"))
+		}
+
+		withdrawal.amount -= amount
+		o.balances[withdrawal.user] += amount
+
+		return &database.Transaction{
+			OrderNumber: orderID,
+			UserLogin:   withdrawal.user,
+			Type:        database.TransactionTypeRefund,
+			Amount:      amount,
+			CreatedAt:   database.CustomDateTime{Time: time.Now()},
+			MerchantID:  merchant,
+		}, nil
+	}
+
+	return nil, database.NewDBRefundError(orderID, true, false, errors.New("списание по заказу "+orderID+" не найдено"))
+}
+
+func TestWithdrawMerchant(t *testing.T) {
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		wantStatus   int
+		wantCode     string
+		wantMerchant string
+	}{
+		{
+			name:       "без ключа магазина",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "магазин определяется по ключу интеграции",
+			headers:      map[string]string{merchantKeyHeader: "shop-key"},
+			wantStatus:   http.StatusOK,
+			wantMerchant: "shop",
+		},
+		{
+			name:       "неверный ключ магазина",
+			headers:    map[string]string{merchantKeyHeader: "shop"},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   ErrorCodeInvalidMerchantKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newFakeOrders(map[string]float32{alice.login: 100})
+			h := newTestHandler(o, nil)
+
+			response := doRequest(h, http.MethodPost, "/api/user/balance/withdraw", &alice, `{"order":"2377225624","sum":10,"merchant":"other"}`, tt.headers)
+			checkResponse(t, response, tt.wantStatus, tt.wantCode)
+
+			if tt.wantStatus != http.StatusOK {
+				if len(o.withdrawals) != 0 {
+					t.Errorf("списания = %+v, ожидалось отсутствие списаний", o.withdrawals)
+				}
+				return
+			}
+
+			if len(o.withdrawals) != 1 || o.withdrawals[0].merchant != tt.wantMerchant {
+				t.Errorf("списания = %+v, ожидался магазин '%v'", o.withdrawals, tt.wantMerchant)
+			}
+		})
+	}
+}
+
+func TestRefundWithdrawalScope(t *testing.T) {
+	o := newFakeOrders(map[string]float32{alice.login: 100})
+	h := newTestHandler(o, nil)
+
+	response := doRequest(h, http.MethodPost, "/api/user/balance/withdraw", &alice, `{"order":"2377225624","sum":40}`, map[string]string{merchantKeyHeader: "shop-key"})
+	checkResponse(t, response, http.StatusOK, "")
+
+	tests := []struct {
+		name       string
+		order      string
+		headers    map[string]string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "без ключа магазина",
+			order:      "2377225624",
+			body:       `{"sum":10}`,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   ErrorCodeInvalidMerchantKey,
+		},
+		{
+			name:       "пользовательский токен вместо ключа магазина",
+			order:      "2377225624",
+			headers:    map[string]string{"Authorization": alice.token},
+			body:       `{"sum":10}`,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   ErrorCodeInvalidMerchantKey,
+		},
+		{
+			name:       "списание другого магазина",
+			order:      "2377225624",
+			headers:    map[string]string{merchantKeyHeader: "other-key"},
+			body:       `{"sum":10}`,
+			wantStatus: http.StatusNotFound,
+			wantCode:   ErrorCodeWithdrawalNotFound,
+		},
+		{
+			name:       "неизвестный заказ",
+			order:      "79927398713",
+			headers:    map[string]string{merchantKeyHeader: "shop-key"},
+			body:       `{"sum":10}`,
+			wantStatus: http.StatusNotFound,
+			wantCode:   ErrorCodeWithdrawalNotFound,
+		},
+		{
+			name:       "возврат больше списания",
+			order:      "2377225624",
+			headers:    map[string]string{merchantKeyHeader: "shop-key"},
+			body:       `{"sum":50}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   ErrorCodeRefundExceedsWithdrawal,
+		},
+		{
+			name:       "частичный возврат своего списания",
+			order:      "2377225624",
+			headers:    map[string]string{merchantKeyHeader: "shop-key"},
+			body:       `{"sum":10}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := doRequest(h, http.MethodPost, "/api/merchant/withdrawals/"+tt.order+"/refund", nil, tt.body, tt.headers)
+			checkResponse(t, response, tt.wantStatus, tt.wantCode)
+		})
+	}
+
+	if o.balances[alice.login] != 70 {
+		t.Errorf("баланс = %v, ожидалось 70", o.balances[alice.login])
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/i18n"
+	"github.com/go-chi/chi/v5"
+)
+
+type RedemptionRequestBody struct {
+	ItemID   int64 `json:"item_id"`
+	Quantity int32 `json:"quantity,omitempty"`
+}
+
+type RedemptionStatusRequestBody struct {
+	Status string `json:"status"`
+}
+
+func (h *Handler) getRewards(w http.ResponseWriter, r *http.Request) {
+	items, err := h.rewards.GetItems(true)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение каталога вознаграждений: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, items, http.StatusOK)
+}
+
+func (h *Handler) getAdminRewards(w http.ResponseWriter, r *http.Request) {
+	items, err := h.rewards.GetItems(false)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение каталога вознаграждений: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, items, http.StatusOK)
+}
+
+func (h *Handler) getReward(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор вознаграждения:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	item, err := h.rewards.GetItem(itemID)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение вознаграждения: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, item, http.StatusOK)
+}
+
+func (h *Handler) createReward(w http.ResponseWriter, r *http.Request) {
+	item, ok := decodeRewardItem(w, r)
+	if !ok {
+		return
+	}
+
+	log.Println("Администратор " + currentUserLogin(r) + " добавляет в каталог вознаграждение " + item.Name)
+
+	err := h.rewards.CreateItem(item)
+	if err != nil {
+		log.Println("Ошибка при добавлении вознаграждения: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, item, http.StatusCreated)
+}
+
+func (h *Handler) updateReward(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор вознаграждения:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	item, ok := decodeRewardItem(w, r)
+	if !ok {
+		return
+	}
+
+	item.ID = itemID
+
+	log.Println("Администратор " + currentUserLogin(r) + " изменяет вознаграждение " + strconv.FormatInt(itemID, 10))
+
+	err = h.rewards.UpdateItem(item)
+	if err != nil {
+		log.Println("Ошибка при изменении вознаграждения: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, item, http.StatusOK)
+}
+
+func (h *Handler) deleteReward(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор вознаграждения:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	log.Println("Администратор " + currentUserLogin(r) + " удаляет вознаграждение " + strconv.FormatInt(itemID, 10))
+
+	err = h.rewards.DeleteItem(itemID)
+	if err != nil {
+		log.Println("Ошибка при удалении вознаграждения: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) createRedemption(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе обмена баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	requestBody := RedemptionRequestBody{}
+	err = json.Unmarshal(request, &requestBody)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе обмена баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	log.Println("Переданные данные для обмена баллов:", requestBody)
+
+	redemption, err := h.rewards.Redeem(currentUserLogin(r), requestBody.ItemID, requestBody.Quantity)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на обмен баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	redemption.StatusLabel = i18n.StatusLabel(responseLocale(w), redemption.Status)
+
+	writeJSONResponse(w, redemption, http.StatusCreated)
+}
+
+func (h *Handler) getRedemptions(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	redemptions, err := h.rewards.GetRedemptions(currentUserLogin(r))
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение обменов баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	if len(redemptions) == 0 {
+		log.Println("Для пользователя " + currentUserLogin(r) + " не найдено обменов баллов")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	locale := responseLocale(w)
+	for i := range redemptions {
+		redemptions[i].StatusLabel = i18n.StatusLabel(locale, redemptions[i].Status)
+	}
+
+	writeJSONResponse(w, redemptions, http.StatusOK)
+}
+
+func (h *Handler) changeRedemptionStatus(w http.ResponseWriter, r *http.Request) {
+	redemptionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор обмена баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе изменения статуса обмена баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	requestBody := RedemptionStatusRequestBody{}
+	err = json.Unmarshal(request, &requestBody)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе изменения статуса обмена баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	log.Println("Администратор " + currentUserLogin(r) + " изменяет статус обмена баллов " + strconv.FormatInt(redemptionID, 10) + " на " + requestBody.Status)
+
+	redemption, err := h.rewards.ChangeRedemptionStatus(redemptionID, requestBody.Status)
+	if err != nil {
+		log.Println("Ошибка при изменении статуса обмена баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	redemption.StatusLabel = i18n.StatusLabel(responseLocale(w), redemption.Status)
+
+	writeJSONResponse(w, redemption, http.StatusOK)
+}
+
+func decodeRewardItem(w http.ResponseWriter, r *http.Request) (*database.RewardItem, bool) {
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе вознаграждения:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return nil, false
+	}
+
+	item := database.RewardItem{Active: true}
+	err = json.Unmarshal(request, &item)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе вознаграждения:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return nil, false
+	}
+
+	return &item, true
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/export"
+)
+
+func (h *Handler) getStatement(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	filter, parameter, err := parseStatementFilter(r)
+	if err != nil {
+		log.Println("Неверный параметр '"+parameter+"' в запросе выписки по счёту:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, map[string]any{"parameter": parameter})
+		return
+	}
+
+	page, err := h.orders.GetStatement(currentUserLogin(r), filter)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение выписки по счёту: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	if page.Next != nil {
+		h.writeNextPageHeaders(w, r, page.Next.String())
+	}
+
+	if len(page.Entries) == 0 {
+		log.Println("Для пользователя " + currentUserLogin(r) + " не найдено операций по счёту")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSONResponse(w, page.Entries, http.StatusOK)
+}
+
+func (h *Handler) exportStatement(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	contentType, err := export.ContentType(format)
+	if err != nil {
+		log.Println("Неверный параметр 'format' в запросе выгрузки выписки по счёту:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, map[string]any{"parameter": "format"})
+		return
+	}
+
+	from, to, parameter, err := parsePeriod(query)
+	if err != nil {
+		log.Println("Неверный параметр '"+parameter+"' в запросе выгрузки выписки по счёту:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, map[string]any{"parameter": parameter})
+		return
+	}
+
+	statement, err := export.NewStatement(h.orders, currentUserLogin(r), export.Period{From: from, To: to})
+	if err != nil {
+		log.Println("Ошибка при выгрузке выписки по счёту пользователя " + currentUserLogin(r) + ": " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	var document []byte
+	if format == export.FormatPDF {
+		document, err = statement.RenderPDF()
+		if err != nil {
+			log.Println("Ошибка при формировании выписки по счёту пользователя " + currentUserLogin(r) + " в формате PDF: " + err.Error())
+			writeError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\"statement."+format+"\"")
+	w.WriteHeader(http.StatusOK)
+
+	if document != nil {
+		_, err = w.Write(document)
+	} else {
+		err = statement.WriteCSV(w)
+	}
+
+	if err != nil {
+		log.Println("Ошибка при выгрузке выписки по счёту пользователя " + currentUserLogin(r) + ": " + err.Error())
+		return
+	}
+
+	log.Println("Выписка по счёту пользователя " + currentUserLogin(r) + " выгружена в формате " + format)
+}
+
+func parseStatementFilter(r *http.Request) (*database.StatementFilter, string, error) {
+	query := r.URL.Query()
+	filter := database.StatementFilter{}
+
+	for _, transactionType := range parseListParameter(query, "type") {
+		if !isTransactionType(transactionType) {
+			return nil, "type", errors.New("неизвестный тип операции " + transactionType)
+		}
+
+		filter.Types = append(filter.Types, transactionType)
+	}
+
+	from, to, parameter, err := parsePeriod(query)
+	if err != nil {
+		return nil, parameter, err
+	}
+
+	filter.From, filter.To = from, to
+
+	filter.Limit, err = parseLimit(query)
+	if err != nil {
+		return nil, "limit", err
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		filter.After, err = database.ParseStatementCursor(value)
+		if err != nil {
+			return nil, "cursor", err
+		}
+	}
+
+	return &filter, "", nil
+}
+
+func isTransactionType(transactionType string) bool {
+	for _, t := range database.TransactionTypes {
+		if t == transactionType {
+			return true
+		}
+	}
+
+	return false
+}
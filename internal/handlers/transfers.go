@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/i18n"
+	"github.com/go-chi/chi/v5"
+)
+
+type TransferRequestBody struct {
+	Recipient string  `json:"recipient"`
+	Amount    float32 `json:"sum"`
+	Note      string  `json:"note,omitempty"`
+}
+
+type TransferConfirmRequestBody struct {
+	Password string `json:"password"`
+}
+
+func (h *Handler) transferPoints(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе перевода баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	requestBody := TransferRequestBody{}
+	err = json.Unmarshal(request, &requestBody)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе перевода баллов:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	log.Println("Переданные данные для перевода баллов:", requestBody)
+
+	transfer, err := h.orders.Transfer(currentUserLogin(r), requestBody.Recipient, requestBody.Amount, requestBody.Note)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на перевод баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if transfer.Status == database.TransferStatusPending {
+		status = http.StatusAccepted
+	}
+
+	writeTransfer(w, transfer, status)
+}
+
+func (h *Handler) confirmTransfer(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	transferID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор перевода:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе подтверждения перевода:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	requestBody := TransferConfirmRequestBody{}
+	err = json.Unmarshal(request, &requestBody)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе подтверждения перевода:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	passwordConfirmed, err := h.authenticator.VerifyPassword(currentUserLogin(r), requestBody.Password)
+	if err != nil {
+		log.Println("Ошибка при проверке пароля для подтверждения перевода: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	transfer, err := h.orders.ConfirmTransfer(currentUserLogin(r), transferID, passwordConfirmed)
+	if err != nil {
+		log.Println("Ошибка при подтверждении перевода баллов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeTransfer(w, transfer, http.StatusOK)
+}
+
+func (h *Handler) getTransfers(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	transfers, err := h.orders.GetTransfers(currentUserLogin(r))
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение списка переводов: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	if len(transfers) == 0 {
+		log.Println("Для пользователя " + currentUserLogin(r) + " не найдено переводов")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	locale := responseLocale(w)
+	for i := range transfers {
+		transfers[i].StatusLabel = i18n.StatusLabel(locale, transfers[i].Status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(transfers)
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
+}
+
+func writeTransfer(w http.ResponseWriter, transfer *database.Transfer, status int) {
+	transfer.StatusLabel = i18n.StatusLabel(responseLocale(w), transfer.Status)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(transfer)
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
+		return
+	}
+
+	w.WriteHeader(status)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
+}
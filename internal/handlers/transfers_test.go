@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/orders"
+)
+
+const testConfirmationThreshold = 50
+
+func (o *fakeOrders) Transfer(user, recipient string, amount float32, note string) (*database.Transfer, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if _, found := o.balances[recipient]; !found {
+		return nil, database.NewDBTransferError(0, user, false, true, false, false, false, errors.New("получатель перевода "+recipient+" не найден"))
+	}
+
+	if amount > o.balances[user]-o.held[user] {
+		return nil, orders.NewOrderError("", false, false, true, user, errors.New("недостаточно средств для перевода"))
+	}
+
+	transfer := database.Transfer{
+		ID:        int64(len(o.transfers) + 1),
+		Sender:    user,
+		Recipient: recipient,
+		Amount:    amount,
+		Note:      note,
+		Status:    database.TransferStatusCompleted,
+		CreatedAt: database.CustomDateTime{Time: time.Now()},
+	}
+
+	if amount >= testConfirmationThreshold {
+		transfer.Status = database.TransferStatusPending
+	} else {
+		o.balances[user] -= amount
+		o.balances[recipient] += amount
+	}
+
+	o.transfers = append(o.transfers, transfer)
+
+	return &transfer, nil
+}
+
+func (o *fakeOrders) ConfirmTransfer(user string, transferID int64, passwordConfirmed bool) (*database.Transfer, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if transferID < 1 || transferID > int64(len(o.transfers)) || o.transfers[transferID-1].Sender != user {
+		return nil, database.NewDBTransferError(transferID, user, true, false, false, false, false, errors.New("перевод не найден"))
+	}
+
+	transfer := &o.transfers[transferID-1]
+	if transfer.Status != database.TransferStatusPending {
+		return nil, database.NewDBTransferError(transferID, user, false, false, true, false, false, errors.New("перевод уже завершён"))
+	}
+
+	if !passwordConfirmed {
+		return nil, database.NewDBTransferError(transferID, user, false, false, false, true, false, errors.New("неверный пароль для подтверждения перевода"))
+	}
+
+	transfer.Status = database.TransferStatusCompleted
+	o.balances[transfer.Sender] -= transfer.Amount
+	o.balances[transfer.Recipient] += transfer.Amount
+
+	result := *transfer
+	return &result, nil
+}
+
+func TestTransferAndConfirm(t *testing.T) {
+	o := newFakeOrders(map[string]float32{alice.login: 100, bob.login: 0})
+	h := newTestHandler(o, nil)
+
+	response := doRequest(h, http.MethodPost, "/api/user/balance/transfer", &alice, `{"recipient":"carol","sum":10}`, nil)
+	checkResponse(t, response, http.StatusNotFound, ErrorCodeRecipientNotFound)
+
+	response = doRequest(h, http.MethodPost, "/api/user/balance/transfer", &alice, `{"recipient":"bob","sum":10}`, nil)
+	checkResponse(t, response, http.StatusOK, "")
+
+	response = doRequest(h, http.MethodPost, "/api/user/balance/transfer", &alice, `{"recipient":"bob","sum":60}`, nil)
+	checkResponse(t, response, http.StatusAccepted, "")
+
+	if strings.Contains(response.Body.String(), "code") {
+		t.Errorf("ответ на создание перевода содержит код подтверждения: %v", response.Body.String())
+	}
+
+	var pending database.Transfer
+	err := json.Unmarshal(response.Body.Bytes(), &pending)
+	if err != nil {
+		t.Fatalf("не удалось разобрать ответ на создание перевода: %v", err)
+	}
+
+	confirmPath := "/api/user/balance/transfer/" + strconv.FormatInt(pending.ID, 10) + "/confirm"
+
+	tests := []struct {
+		name       string
+		user       *testUser
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "без аутентификации",
+			path:       confirmPath,
+			body:       `{"password":"alice-password"}`,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   ErrorCodeUnauthorized,
+		},
+		{
+			name:       "неверный идентификатор перевода",
+			user:       &alice,
+			path:       "/api/user/balance/transfer/abc/confirm",
+			body:       `{"password":"alice-password"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrorCodeBadRequest,
+		},
+		{
+			name:       "перевод другого пользователя",
+			user:       &bob,
+			path:       confirmPath,
+			body:       `{"password":"bob-password"}`,
+			wantStatus: http.StatusNotFound,
+			wantCode:   ErrorCodeTransferNotFound,
+		},
+		{
+			name:       "неверный пароль",
+			user:       &alice,
+			path:       confirmPath,
+			body:       `{"password":"bob-password"}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   ErrorCodeInvalidTransferPassword,
+		},
+		{
+			name:       "подтверждение паролем",
+			user:       &alice,
+			path:       confirmPath,
+			body:       `{"password":"alice-password"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "повторное подтверждение",
+			user:       &alice,
+			path:       confirmPath,
+			body:       `{"password":"alice-password"}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   ErrorCodeTransferInactive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := doRequest(h, http.MethodPost, tt.path, tt.user, tt.body, nil)
+			checkResponse(t, response, tt.wantStatus, tt.wantCode)
+		})
+	}
+
+	if o.balances[alice.login] != 30 || o.balances[bob.login] != 70 {
+		t.Errorf("балансы = %v, ожидалось alice: 30, bob: 70", o.balances)
+	}
+}
@@ -1,47 +1,56 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 )
 
+type contextKey string
+
+const userLoginKey contextKey = "userLogin"
+
 type UserRequestBody struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login        string `json:"login"`
+	Password     string `json:"password"`
+	ReferralCode string `json:"referral_code,omitempty"`
 }
 
 func (h *Handler) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		h.currentUserLogin = ""
-
 		token := r.Header.Get("Authorization")
 		if token == "" || h.authenticator == nil {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		var err error
-		h.currentUserLogin, err = h.authenticator.Authenticate(token)
+		login, err := h.authenticator.Authenticate(token)
 		if err != nil {
-			log.Println("Пользователь не аутентифицирован")
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			log.Println("Пользователь не аутентифицирован:", err)
+			writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
 			return
 		}
 
 		w.Header().Set("Authorization", token)
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userLoginKey, login)))
 	})
 }
 
+func currentUserLogin(r *http.Request) string {
+	login, _ := r.Context().Value(userLoginKey).(string)
+	return login
+}
+
 func (h *Handler) registerUser(w http.ResponseWriter, r *http.Request) {
 	request, err := decodeRequest(r)
 	if err != nil {
 		log.Println("Неверный формат данных в запросе регистрации пользователя:", err)
-		http.Error(w, "неверный формат данных в запросе регистрации пользователя: "+err.Error(), http.StatusBadRequest)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
 		return
 	}
 
@@ -49,24 +58,32 @@ func (h *Handler) registerUser(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(request, &requestBody)
 	if err != nil {
 		log.Println("Неверный формат данных в запросе регистрации пользователя:", err)
-		http.Error(w, "неверный формат данных в запросе регистрации пользователя: "+err.Error(), http.StatusBadRequest)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
 		return
 	}
 	log.Println("Переданные данные для регистрации пользователя:", requestBody)
 
-	token, err := h.authenticator.Register(requestBody.Login, requestBody.Password)
-	if err != nil && errors.Is(err, database.DBUserError{User: requestBody.Login, Duplicate: true, Err: nil}) {
-		log.Println("Логин уже занят:", err)
-		http.Error(w, "логин уже занят: "+err.Error(), http.StatusConflict)
-		return
+	if requestBody.ReferralCode != "" {
+		err = h.orders.CheckReferralCode(requestBody.ReferralCode)
+		if err != nil {
+			log.Println("Ошибка при проверке реферального кода:", err)
+			writeError(w, err)
+			return
+		}
 	}
 
+	token, err := h.authenticator.Register(requestBody.Login, requestBody.Password)
 	if err != nil {
 		log.Println("Ошибка в сервисе регистрации пользователя:", err)
-		http.Error(w, "ошибка в сервисе регистрации пользователя: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
+	err = h.orders.SetupNewUser(requestBody.Login, requestBody.ReferralCode, h.clientIP(r))
+	if err != nil {
+		log.Println("Ошибка при начислении бонусов новому пользователю "+requestBody.Login+":", err)
+	}
+
 	w.Header().Set("Authorization", token)
 	w.WriteHeader(http.StatusOK)
 }
@@ -75,7 +92,7 @@ func (h *Handler) loginUser(w http.ResponseWriter, r *http.Request) {
 	request, err := decodeRequest(r)
 	if err != nil {
 		log.Println("Неверный формат данных в запросе авторизации пользователя:", err)
-		http.Error(w, "неверный формат данных в запросе авторизации пользователя: "+err.Error(), http.StatusBadRequest)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
 		return
 	}
 
@@ -83,24 +100,119 @@ func (h *Handler) loginUser(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(request, &requestBody)
 	if err != nil {
 		log.Println("Неверный формат данных в запросе авторизации пользователя:", err)
-		http.Error(w, "неверный формат данных в запросе авторизации пользователя: "+err.Error(), http.StatusBadRequest)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
 		return
 	}
 	log.Println("Переданные данные для авторизации пользователя:", requestBody)
 
 	token, err := h.authenticator.Login(requestBody.Login, requestBody.Password)
-	if err != nil && errors.Is(err, database.DBUserError{User: requestBody.Login, Duplicate: false, Err: nil}) {
-		log.Println("Неверная пара логин/пароль:", err)
-		http.Error(w, "неверная пара логин/пароль: "+err.Error(), http.StatusUnauthorized)
+	if err != nil {
+		log.Println("Ошибка в сервисе авторизации пользователя:", err)
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Authorization", token)
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseNetwork(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		return network, err
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.New("неверный IP-адрес '" + s + "'")
+	}
+
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func (h *Handler) isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range h.proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Handler) clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	if !h.isTrustedProxy(remote) {
+		return remote
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip != "" && !h.isTrustedProxy(ip) {
+			return ip
+		}
+	}
+
+	realIP := strings.TrimSpace(r.Header.Get("X-Real-IP"))
+	if realIP != "" {
+		return realIP
+	}
+
+	return remote
+}
+
+func (h *Handler) rateLimitIP(r *http.Request) string {
+	ip := h.clientIP(r)
+	if h.isTrustedProxy(ip) {
+		return ""
+	}
+
+	return ip
+}
+
+func (h *Handler) getReferral(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
 		return
 	}
 
+	stats, err := h.orders.GetReferralStats(currentUserLogin(r))
 	if err != nil {
-		log.Println("Ошибка в сервисе авторизации пользователя:", err)
-		http.Error(w, "ошибка в сервисе авторизации пользователя: "+err.Error(), http.StatusInternalServerError)
+		log.Println("Ошибка при обработке запроса на получение реферальной программы: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := json.Marshal(stats)
+	if err != nil {
+		log.Println("Ошибка при формировании ответа:", err)
+		writeErrorResponse(w, http.StatusInternalServerError, ErrorCodeInternal, nil)
 		return
 	}
 
-	w.Header().Set("Authorization", token)
 	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(response)
+	if err != nil {
+		log.Println("Ошибка при записи ответа в тело запроса:", err)
+	}
 }
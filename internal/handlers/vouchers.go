@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/vouchers"
+)
+
+type VoucherRedeemRequestBody struct {
+	Code string `json:"code"`
+}
+
+type VoucherRedeemResponseBody struct {
+	Amount float32 `json:"sum"`
+}
+
+func (h *Handler) redeemVoucher(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе активации ваучера:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	requestBody := VoucherRedeemRequestBody{}
+	err = json.Unmarshal(request, &requestBody)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе активации ваучера:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	voucher, err := h.vouchers.Redeem(currentUserLogin(r), requestBody.Code, h.rateLimitIP(r))
+	if err != nil {
+		log.Println("Ошибка при активации ваучера пользователем " + currentUserLogin(r) + ": " + err.Error())
+
+		var voucherError *vouchers.VoucherError
+		if errors.As(err, &voucherError) && voucherError.Invalid {
+			writeErrorResponse(w, http.StatusNotFound, ErrorCodeVoucherNotFound, nil)
+			return
+		}
+
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, VoucherRedeemResponseBody{Amount: voucher.Value}, http.StatusOK)
+}
+
+func (h *Handler) createVoucherBatch(w http.ResponseWriter, r *http.Request) {
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе создания ваучеров:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	batch := database.VoucherBatch{}
+	err = json.Unmarshal(request, &batch)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе создания ваучеров:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	batch.CreatedBy = currentUserLogin(r)
+
+	log.Println("Администратор " + currentUserLogin(r) + " создаёт партию ваучеров")
+
+	err = h.vouchers.GenerateBatch(&batch)
+	if err != nil {
+		log.Println("Ошибка при создании партии ваучеров: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, &batch, http.StatusCreated)
+}
+
+func (h *Handler) getVoucherBatches(w http.ResponseWriter, r *http.Request) {
+	batches, err := h.vouchers.GetBatches()
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение партий ваучеров: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, batches, http.StatusOK)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhookRequestBody struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"`
+	Active     *bool    `json:"active,omitempty"`
+}
+
+func (h *Handler) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	webhooks, err := h.webhooks.GetWebhooks(currentUserLogin(r))
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение вебхуков: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	if len(webhooks) == 0 {
+		log.Println("Вебхуки для пользователя " + currentUserLogin(r) + " не найдены")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	writeJSONResponse(w, webhooks, http.StatusOK)
+}
+
+func (h *Handler) getWebhook(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор вебхука:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	webhook, err := h.webhooks.GetWebhook(currentUserLogin(r), webhookID)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение вебхука: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	webhook.Secret = ""
+
+	writeJSONResponse(w, webhook, http.StatusOK)
+}
+
+func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	webhook, ok := decodeWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	webhook.UserLogin = currentUserLogin(r)
+
+	err := h.webhooks.CreateWebhook(webhook)
+	if err != nil {
+		log.Println("Ошибка при регистрации вебхука: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	writeJSONResponse(w, webhook, http.StatusCreated)
+}
+
+func (h *Handler) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор вебхука:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	webhook, ok := decodeWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	webhook.ID = webhookID
+	webhook.UserLogin = currentUserLogin(r)
+
+	err = h.webhooks.UpdateWebhook(webhook)
+	if err != nil {
+		log.Println("Ошибка при изменении вебхука: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	webhook.Secret = ""
+
+	writeJSONResponse(w, webhook, http.StatusOK)
+}
+
+func (h *Handler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор вебхука:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	err = h.webhooks.DeleteWebhook(currentUserLogin(r), webhookID)
+	if err != nil {
+		log.Println("Ошибка при удалении вебхука: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if currentUserLogin(r) == "" {
+		log.Println("Пользователь не аутентифицирован")
+		writeErrorResponse(w, http.StatusUnauthorized, ErrorCodeUnauthorized, nil)
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println("Неверный идентификатор вебхука:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return
+	}
+
+	deliveries, err := h.webhooks.GetDeliveries(currentUserLogin(r), webhookID)
+	if err != nil {
+		log.Println("Ошибка при обработке запроса на получение журнала доставки вебхука: " + err.Error())
+		writeError(w, err)
+		return
+	}
+
+	if len(deliveries) == 0 {
+		log.Println("Журнал доставки вебхука " + strconv.FormatInt(webhookID, 10) + " пуст")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSONResponse(w, deliveries, http.StatusOK)
+}
+
+func decodeWebhook(w http.ResponseWriter, r *http.Request) (*database.Webhook, bool) {
+	request, err := decodeRequest(r)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе вебхука:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return nil, false
+	}
+
+	requestBody := WebhookRequestBody{}
+	err = json.Unmarshal(request, &requestBody)
+	if err != nil {
+		log.Println("Неверный формат данных в запросе вебхука:", err)
+		writeErrorResponse(w, http.StatusBadRequest, ErrorCodeBadRequest, nil)
+		return nil, false
+	}
+
+	webhook := database.Webhook{
+		URL:        requestBody.URL,
+		EventTypes: requestBody.EventTypes,
+		Secret:     requestBody.Secret,
+		Active:     true,
+	}
+
+	if requestBody.Active != nil {
+		webhook.Active = *requestBody.Active
+	}
+
+	return &webhook, true
+}
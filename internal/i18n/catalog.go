@@ -0,0 +1,181 @@
+package i18n
+
+var catalog = map[string]map[string]string{
+	LocaleRussian: {
+		"bad_request":                     "неверный формат данных в запросе",
+		"unsupported_request":             "неподдерживаемый запрос",
+		"unauthorized":                    "пользователь не аутентифицирован",
+		"forbidden":                       "недостаточно прав для выполнения запроса",
+		"invalid_merchant_key":            "неверный ключ интеграции магазина",
+		"internal_error":                  "внутренняя ошибка сервиса, повторите запрос позже",
+		"login_taken":                     "логин уже занят",
+		"invalid_credentials":             "неверная пара логин/пароль",
+		"invalid_referral_code":           "неверный реферальный код",
+		"invalid_order_number":            "неверный формат номера заказа",
+		"order_owned_by_another_user":     "номер заказа уже загружен другим пользователем",
+		"order_not_found":                 "заказ не найден",
+		"order_status_conflict":           "изменение статуса заказа недопустимо",
+		"invalid_amount":                  "неверно указана сумма",
+		"insufficient_funds":              "на счёте недостаточно баллов",
+		"withdrawal_rule_violated":        "списание баллов отклонено правилами списания",
+		"withdrawal_not_found":            "списание баллов по заказу не найдено",
+		"refund_exceeds_withdrawal":       "сумма возврата превышает сумму списания",
+		"hold_not_found":                  "резерв баллов не найден",
+		"hold_inactive":                   "резерв баллов уже завершён",
+		"hold_exists":                     "для заказа уже создан резерв баллов",
+		"invalid_transfer":                "неверные параметры перевода баллов",
+		"invalid_recipient":               "неверно указан получатель перевода",
+		"transfer_not_found":              "перевод баллов не найден",
+		"recipient_not_found":             "получатель перевода не найден",
+		"transfer_inactive":               "перевод баллов уже завершён",
+		"invalid_transfer_password":       "неверный пароль для подтверждения перевода",
+		"transfer_limit_exceeded":         "превышен дневной лимит переводов баллов",
+		"campaign_not_found":              "акция не найдена",
+		"invalid_campaign":                "неверные параметры акции",
+		"reward_not_found":                "вознаграждение не найдено",
+		"invalid_reward":                  "неверные параметры вознаграждения",
+		"reward_unavailable":              "вознаграждение недоступно для обмена",
+		"redemption_status_conflict":      "изменение статуса обмена баллов недопустимо",
+		"invalid_voucher_batch":           "неверные параметры партии ваучеров",
+		"voucher_not_found":               "ваучер не найден",
+		"voucher_redeemed":                "ваучер уже активирован",
+		"voucher_expired":                 "срок действия ваучера истёк",
+		"rate_limited":                    "слишком много запросов, повторите позже",
+		"invalid_idempotency_key":         "неверный ключ идемпотентности",
+		"idempotency_key_reused":          "ключ идемпотентности уже использован для другого запроса",
+		"idempotency_request_in_progress": "запрос с этим ключом идемпотентности ещё обрабатывается",
+		"webhook_not_found":               "вебхук не найден",
+		"invalid_webhook":                 "неверные параметры вебхука",
+
+		"status.NEW":        "Новый",
+		"status.PROCESSING": "В обработке",
+		"status.PROCESSED":  "Обработан",
+		"status.INVALID":    "Отклонён",
+		"status.PENDING":    "Ожидает",
+		"status.COMPLETED":  "Выполнен",
+		"status.EXPIRED":    "Истёк",
+		"status.ACTIVE":     "Активен",
+		"status.CAPTURED":   "Списан",
+		"status.VOIDED":     "Отменён",
+		"status.FULFILLED":  "Выдан",
+		"status.CANCELLED":  "Отменён",
+	},
+	LocaleEnglish: {
+		"bad_request":                     "malformed request data",
+		"unsupported_request":             "unsupported request",
+		"unauthorized":                    "user is not authenticated",
+		"forbidden":                       "insufficient permissions for this request",
+		"invalid_merchant_key":            "invalid merchant integration key",
+		"internal_error":                  "internal service error, please try again later",
+		"login_taken":                     "login is already taken",
+		"invalid_credentials":             "invalid login/password pair",
+		"invalid_referral_code":           "invalid referral code",
+		"invalid_order_number":            "invalid order number format",
+		"order_owned_by_another_user":     "order number has already been uploaded by another user",
+		"order_not_found":                 "order not found",
+		"order_status_conflict":           "order status change is not allowed",
+		"invalid_amount":                  "invalid amount",
+		"insufficient_funds":              "insufficient points on the account",
+		"withdrawal_rule_violated":        "withdrawal rejected by withdrawal rules",
+		"withdrawal_not_found":            "no withdrawal found for the order",
+		"refund_exceeds_withdrawal":       "refund amount exceeds the withdrawn amount",
+		"hold_not_found":                  "points hold not found",
+		"hold_inactive":                   "points hold is already finished",
+		"hold_exists":                     "a points hold already exists for the order",
+		"invalid_transfer":                "invalid points transfer parameters",
+		"invalid_recipient":               "invalid transfer recipient",
+		"transfer_not_found":              "points transfer not found",
+		"recipient_not_found":             "transfer recipient not found",
+		"transfer_inactive":               "points transfer is already finished",
+		"invalid_transfer_password":       "invalid password for transfer confirmation",
+		"transfer_limit_exceeded":         "daily points transfer limit exceeded",
+		"campaign_not_found":              "campaign not found",
+		"invalid_campaign":                "invalid campaign parameters",
+		"reward_not_found":                "reward not found",
+		"invalid_reward":                  "invalid reward parameters",
+		"reward_unavailable":              "reward is not available for redemption",
+		"redemption_status_conflict":      "redemption status change is not allowed",
+		"invalid_voucher_batch":           "invalid voucher batch parameters",
+		"voucher_not_found":               "voucher not found",
+		"voucher_redeemed":                "voucher has already been redeemed",
+		"voucher_expired":                 "voucher has expired",
+		"rate_limited":                    "too many requests, please try again later",
+		"invalid_idempotency_key":         "invalid idempotency key",
+		"idempotency_key_reused":          "idempotency key has already been used for a different request",
+		"idempotency_request_in_progress": "a request with this idempotency key is still being processed",
+		"webhook_not_found":               "webhook not found",
+		"invalid_webhook":                 "invalid webhook parameters",
+
+		"status.NEW":        "New",
+		"status.PROCESSING": "Processing",
+		"status.PROCESSED":  "Processed",
+		"status.INVALID":    "Rejected",
+		"status.PENDING":    "Pending",
+		"status.COMPLETED":  "Completed",
+		"status.EXPIRED":    "Expired",
+		"status.ACTIVE":     "Active",
+		"status.CAPTURED":   "Captured",
+		"status.VOIDED":     "Voided",
+		"status.FULFILLED":  "Fulfilled",
+		"status.CANCELLED":  "Cancelled",
+	},
+	LocaleKazakh: {
+		"bad_request":                     "сұраныстағы деректер пішімі қате",
+		"unsupported_request":             "қолдау көрсетілмейтін сұраныс",
+		"unauthorized":                    "пайдаланушы аутентификациядан өтпеген",
+		"forbidden":                       "сұранысты орындауға құқық жеткіліксіз",
+		"invalid_merchant_key":            "дүкен интеграциясының кілті қате",
+		"internal_error":                  "сервистің ішкі қатесі, сұранысты кейінірек қайталаңыз",
+		"login_taken":                     "логин бос емес",
+		"invalid_credentials":             "логин немесе құпиясөз қате",
+		"invalid_referral_code":           "реферал коды қате",
+		"invalid_order_number":            "тапсырыс нөмірінің пішімі қате",
+		"order_owned_by_another_user":     "тапсырыс нөмірін басқа пайдаланушы жүктеген",
+		"order_not_found":                 "тапсырыс табылмады",
+		"order_status_conflict":           "тапсырыс мәртебесін өзгертуге болмайды",
+		"invalid_amount":                  "сома қате көрсетілген",
+		"insufficient_funds":              "шотта ұпай жеткіліксіз",
+		"withdrawal_rule_violated":        "ұпайды есептен шығару ережелерге сәйкес қабылданбады",
+		"withdrawal_not_found":            "тапсырыс бойынша ұпай есептен шығару табылмады",
+		"refund_exceeds_withdrawal":       "қайтару сомасы есептен шығарылған сомадан асады",
+		"hold_not_found":                  "ұпай резерві табылмады",
+		"hold_inactive":                   "ұпай резерві аяқталған",
+		"hold_exists":                     "тапсырыс үшін ұпай резерві бұрыннан бар",
+		"invalid_transfer":                "ұпай аудару параметрлері қате",
+		"invalid_recipient":               "аударым алушысы қате көрсетілген",
+		"transfer_not_found":              "ұпай аударымы табылмады",
+		"recipient_not_found":             "аударым алушысы табылмады",
+		"transfer_inactive":               "ұпай аударымы аяқталған",
+		"invalid_transfer_password":       "аударымды растау құпиясөзі қате",
+		"transfer_limit_exceeded":         "ұпай аударудың күндік лимиті асып кетті",
+		"campaign_not_found":              "акция табылмады",
+		"invalid_campaign":                "акция параметрлері қате",
+		"reward_not_found":                "сыйлық табылмады",
+		"invalid_reward":                  "сыйлық параметрлері қате",
+		"reward_unavailable":              "сыйлықты айырбастау қолжетімсіз",
+		"redemption_status_conflict":      "ұпай айырбасының мәртебесін өзгертуге болмайды",
+		"invalid_voucher_batch":           "ваучерлер партиясының параметрлері қате",
+		"voucher_not_found":               "ваучер табылмады",
+		"voucher_redeemed":                "ваучер бұрын белсендірілген",
+		"voucher_expired":                 "ваучердің жарамдылық мерзімі өтті",
+		"rate_limited":                    "сұраныстар тым көп, кейінірек қайталаңыз",
+		"invalid_idempotency_key":         "идемпотенттілік кілті қате",
+		"idempotency_key_reused":          "идемпотенттілік кілті басқа сұраныс үшін қолданылған",
+		"idempotency_request_in_progress": "осы идемпотенттілік кілтімен сұраныс әлі өңделуде",
+		"webhook_not_found":               "вебхук табылмады",
+		"invalid_webhook":                 "вебхук параметрлері қате",
+
+		"status.NEW":        "Жаңа",
+		"status.PROCESSING": "Өңделуде",
+		"status.PROCESSED":  "Өңделді",
+		"status.INVALID":    "Қабылданбады",
+		"status.PENDING":    "Күтуде",
+		"status.COMPLETED":  "Орындалды",
+		"status.EXPIRED":    "Мерзімі өтті",
+		"status.ACTIVE":     "Белсенді",
+		"status.CAPTURED":   "Есептен шығарылды",
+		"status.VOIDED":     "Болдырылмады",
+		"status.FULFILLED":  "Берілді",
+		"status.CANCELLED":  "Болдырылмады",
+	},
+}
@@ -0,0 +1,62 @@
+package i18n
+
+import (
+	"golang.org/x/text/language"
+)
+
+const (
+	LocaleRussian = "ru"
+	LocaleEnglish = "en"
+	LocaleKazakh  = "kk"
+
+	DefaultLocale = LocaleRussian
+
+	statusKeyPrefix = "status."
+)
+
+var locales = []string{LocaleRussian, LocaleEnglish, LocaleKazakh}
+
+var matcher = language.NewMatcher([]language.Tag{language.Russian, language.English, language.Kazakh})
+
+func Negotiate(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return DefaultLocale
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No || index < 0 || index >= len(locales) {
+		return DefaultLocale
+	}
+
+	return locales[index]
+}
+
+func Message(locale, key string) string {
+	if message, ok := catalog[locale][key]; ok {
+		return message
+	}
+
+	if message, ok := catalog[DefaultLocale][key]; ok {
+		return message
+	}
+
+	return key
+}
+
+func StatusLabel(locale, status string) string {
+	if status == "" {
+		return ""
+	}
+
+	label := Message(locale, statusKeyPrefix+status)
+	if label == statusKeyPrefix+status {
+		return status
+	}
+
+	return label
+}
@@ -0,0 +1,23 @@
+package idempotency
+
+type KeyError struct {
+	Key        string
+	Invalid    bool
+	Mismatch   bool
+	InProgress bool
+	Err        error
+}
+
+func (e KeyError) Error() string {
+	return e.Err.Error()
+}
+
+func NewKeyError(key string, invalid, mismatch, inProgress bool, err error) error {
+	return &KeyError{
+		Key:        key,
+		Invalid:    invalid,
+		Mismatch:   mismatch,
+		InProgress: inProgress,
+		Err:        err,
+	}
+}
@@ -0,0 +1,184 @@
+package idempotency
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	maxKeyLength         = 255
+	keyTTL               = 24 * time.Hour
+	keyLease             = time.Minute
+	keyLeaseRenewal      = keyLease / 3
+	ownerTokenBytes      = 16
+	delayForExpiringKeys = 60 * 60
+)
+
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type KeyManager interface {
+	Begin(user, key, method, path string, body []byte) (*Response, string, error)
+	KeepAlive(user, key, token string) func()
+	Complete(user, key, token string, response *Response) error
+	Abort(user, key, token string) error
+	Close()
+}
+
+type keyController struct {
+	model database.Storager
+	done  chan struct{}
+}
+
+func NewIdempotency(m database.Storager) (KeyManager, error) {
+	if m == nil {
+		return nil, errors.New("не задано хранилище ключей идемпотентности")
+	}
+
+	result := &keyController{
+		model: m,
+		done:  make(chan struct{}),
+	}
+
+	go result.expireKeys()
+
+	return result, nil
+}
+
+func requestHash(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func newOwnerToken() (string, error) {
+	token := make([]byte, ownerTokenBytes)
+
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+func (c *keyController) Begin(user, key, method, path string, body []byte) (*Response, string, error) {
+	if len(key) > maxKeyLength {
+		return nil, "", NewKeyError(key, true, false, false, errors.New("длина ключа идемпотентности превышает "+strconv.Itoa(maxKeyLength)+" символов"))
+	}
+
+	token, err := newOwnerToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	lockedUntil := now.Add(keyLease)
+	record := database.IdempotencyRecord{
+		UserLogin:   user,
+		Key:         key,
+		RequestHash: requestHash(method, path, body),
+		CreatedAt:   now,
+		LockedUntil: &lockedUntil,
+		OwnerToken:  token,
+	}
+
+	existing, err := c.model.ReserveIdempotencyKey(&record, now.Add(-keyTTL))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if existing == nil {
+		return nil, token, nil
+	}
+
+	if existing.RequestHash != record.RequestHash {
+		return nil, "", NewKeyError(key, false, true, false, errors.New("ключ идемпотентности '"+key+"' уже использован для другого запроса"))
+	}
+
+	if existing.StatusCode == 0 {
+		return nil, "", NewKeyError(key, false, false, true, errors.New("запрос с ключом идемпотентности '"+key+"' ещё обрабатывается"))
+	}
+
+	log.Printf("Повтор запроса с ключом идемпотентности '%v' пользователя '%v', возвращается сохранённый ответ\n", key, user)
+
+	return &Response{
+		StatusCode:  existing.StatusCode,
+		ContentType: existing.ContentType,
+		Body:        existing.Body,
+	}, "", nil
+}
+
+func (c *keyController) KeepAlive(user, key, token string) func() {
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(keyLeaseRenewal)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-c.done:
+				return
+			case <-ticker.C:
+			}
+
+			err := c.model.RenewIdempotencyKey(user, key, token, time.Now().Add(keyLease))
+			if err != nil {
+				log.Println("Не удалось продлить блокировку ключа идемпотентности '"+key+"':", err)
+				return
+			}
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
+func (c *keyController) Complete(user, key, token string, response *Response) error {
+	return c.model.CompleteIdempotencyKey(&database.IdempotencyRecord{
+		UserLogin:   user,
+		Key:         key,
+		StatusCode:  response.StatusCode,
+		ContentType: response.ContentType,
+		Body:        response.Body,
+		OwnerToken:  token,
+	})
+}
+
+func (c *keyController) Abort(user, key, token string) error {
+	return c.model.DeleteIdempotencyKey(user, key, token)
+}
+
+func (c *keyController) Close() {
+	close(c.done)
+}
+
+func (c *keyController) expireKeys() {
+	select {
+	case <-c.done:
+		return
+	default:
+
+	}
+
+	err := c.model.DeleteExpiredIdempotencyKeys(time.Now().Add(-keyTTL))
+	if err != nil {
+		log.Println("Ошибка при удалении просроченных ключей идемпотентности:", err)
+	}
+
+	time.AfterFunc(time.Second*delayForExpiringKeys, func() { c.expireKeys() })
+}
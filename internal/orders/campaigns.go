@@ -0,0 +1,63 @@
+package orders
+
+import (
+	"errors"
+	"log"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/campaigns"
+)
+
+func (o *orderController) applyCampaigns(order *Order) {
+	if o.campaigns == nil {
+		return
+	}
+
+	target, err := o.campaignTarget(order)
+	if err != nil {
+		o.errors <- errors.New("ошибка при подготовке данных для акций по заказу " + order.ID + ": " + err.Error())
+		return
+	}
+
+	bonuses, err := o.campaigns.GetBonuses(target)
+	if err != nil {
+		o.errors <- errors.New("ошибка при расчёте бонусов по акциям для заказа " + order.ID + ": " + err.Error())
+		return
+	}
+
+	for _, bonus := range bonuses {
+		err = o.model.AddCampaignBonus(order.UserLogin, order.ID, bonus.CampaignID, bonus.Amount)
+		if err != nil {
+			o.errors <- errors.New("ошибка при начислении бонуса по акции '" + bonus.Name + "' для заказа " + order.ID + ": " + err.Error())
+			continue
+		}
+
+		log.Printf("По заказу '%v' начислен бонус по акции '%v' на сумму '%v'\n", order.ID, bonus.Name, bonus.Amount)
+	}
+}
+
+func (o *orderController) campaignTarget(order *Order) (*campaigns.Target, error) {
+	tier, err := o.getUserTierName(order.UserLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	registeredAt, err := o.model.GetUserRegisteredAt(order.UserLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	firstOrder, err := o.model.IsFirstProcessedOrder(order.UserLogin, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &campaigns.Target{
+		User:         order.UserLogin,
+		OrderID:      order.ID,
+		Tier:         tier,
+		RegisteredAt: registeredAt,
+		FirstOrder:   firstOrder,
+		OrderTime:    order.UploadedAt,
+		Amount:       order.Amount,
+	}, nil
+}
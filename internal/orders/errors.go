@@ -1,6 +1,7 @@
 package orders
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -9,10 +10,41 @@ type OrderError struct {
 	IncorrectID       bool
 	Duplicate         bool
 	InsufficientFunds bool
+	IncorrectAmount   bool
+	NotFound          bool
 	User              string
 	Err               error
 }
 
+type ReferralError struct {
+	Code     string
+	NotFound bool
+	Err      error
+}
+
+type TransferError struct {
+	Recipient          string
+	User               string
+	IncorrectRecipient bool
+	Err                error
+}
+
+type WithdrawalRuleError struct {
+	OrderID   string
+	User      string
+	Rule      string
+	Limit     float32
+	Requested float32
+	Err       error
+}
+
+type OrderStatusError struct {
+	OrderID   string
+	Status    string
+	NewStatus string
+	Err       error
+}
+
 func (e OrderError) Error() string {
 	if e.IncorrectID {
 		return fmt.Sprintf("Неверный формат номера заказа: %v. Ошибка: %v", e.OrderID, e.Err)
@@ -45,3 +77,74 @@ func NewOrderError(orderID string, incorrectID, duplicate, insufficientFunds boo
 		Err:               err,
 	}
 }
+
+func NewOrderNotFoundError(orderID string, user string) error {
+	return &OrderError{
+		OrderID:  orderID,
+		NotFound: true,
+		User:     user,
+		Err:      errors.New("заказ " + orderID + " не найден"),
+	}
+}
+
+func NewOrderAmountError(orderID string, user string, err error) error {
+	return &OrderError{
+		OrderID:         orderID,
+		IncorrectAmount: true,
+		User:            user,
+		Err:             err,
+	}
+}
+
+func (e OrderStatusError) Error() string {
+	return e.Err.Error()
+}
+
+func NewOrderStatusError(orderID, status, newStatus string, err error) error {
+	return &OrderStatusError{
+		OrderID:   orderID,
+		Status:    status,
+		NewStatus: newStatus,
+		Err:       err,
+	}
+}
+
+func (e ReferralError) Error() string {
+	return e.Err.Error()
+}
+
+func NewReferralError(code string, notFound bool, err error) error {
+	return &ReferralError{
+		Code:     code,
+		NotFound: notFound,
+		Err:      err,
+	}
+}
+
+func (e TransferError) Error() string {
+	return e.Err.Error()
+}
+
+func NewTransferError(recipient, user string, incorrectRecipient bool, err error) error {
+	return &TransferError{
+		Recipient:          recipient,
+		User:               user,
+		IncorrectRecipient: incorrectRecipient,
+		Err:                err,
+	}
+}
+
+func (e WithdrawalRuleError) Error() string {
+	return e.Err.Error()
+}
+
+func NewWithdrawalRuleError(orderID, user, rule string, limit, requested float32, err error) error {
+	return &WithdrawalRuleError{
+		OrderID:   orderID,
+		User:      user,
+		Rule:      rule,
+		Limit:     limit,
+		Requested: requested,
+		Err:       err,
+	}
+}
@@ -0,0 +1,35 @@
+package orders
+
+import (
+	"log"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/events"
+)
+
+func (o *orderController) publish(user, eventType string, payload any) {
+	if o.events == nil {
+		return
+	}
+
+	err := o.events.Publish(user, eventType, payload)
+	if err != nil {
+		log.Println("Ошибка при публикации события '"+eventType+"' для пользователя '"+user+"':", err)
+	}
+}
+
+func (o *orderController) publishOrderSaved(order *Order, amount float32) {
+	if order.Status != order.PreviousStatus {
+		o.publish(order.UserLogin, events.EventOrderStatusChanged, events.OrderStatusChanged{
+			Order:          order.ID,
+			Status:         order.Status,
+			PreviousStatus: order.PreviousStatus,
+		})
+	}
+
+	if order.Status == OrderStatusProcessed && !order.Recheck && amount > 0 {
+		o.publish(order.UserLogin, events.EventPointsCredited, events.PointsCredited{
+			Order:  order.ID,
+			Amount: amount,
+		})
+	}
+}
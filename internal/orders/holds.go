@@ -0,0 +1,88 @@
+package orders
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+func (o *orderController) CreateHold(user, orderID string, amount, orderTotal float32, ttl time.Duration) (*database.BalanceHold, error) {
+	err := validateOrderNumber(user, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount <= 0 {
+		return nil, NewOrderAmountError(orderID, user, errors.New("сумма резервирования должна быть больше нуля"))
+	}
+
+	err = o.checkWithdrawalRules(user, orderID, amount, orderTotal)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		ttl = defaultHoldTTL
+	}
+
+	if ttl > maxHoldTTL {
+		ttl = maxHoldTTL
+	}
+
+	now := time.Now()
+	hold := database.BalanceHold{
+		UserLogin:   user,
+		OrderNumber: orderID,
+		Amount:      amount,
+		CreatedAt:   database.CustomDateTime{Time: now},
+		ExpiresAt:   database.CustomDateTime{Time: now.Add(ttl)},
+	}
+
+	var accountError *database.DBAccountError
+	err = o.model.CreateHold(&hold, o.withdrawalLimits())
+	if err != nil && errors.As(err, &accountError) && accountError.InsufficientFunds {
+		return nil, NewOrderError(orderID, false, false, true, user, err)
+	}
+
+	if err != nil {
+		return nil, withdrawalLimitError(orderID, user, amount, err)
+	}
+
+	log.Printf("Создан резерв баллов '%v' для заказа '%v' пользователя '%v' до %v\n", hold.ID, orderID, user, hold.ExpiresAt.Time)
+	return &hold, nil
+}
+
+func (o *orderController) GetHolds(user string) ([]database.BalanceHold, error) {
+	holds, err := o.model.GetHolds(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+func (o *orderController) CaptureHold(user string, holdID int64) error {
+	return o.model.CaptureHold(user, holdID)
+}
+
+func (o *orderController) VoidHold(user string, holdID int64) error {
+	return o.model.VoidHold(user, holdID)
+}
+
+func (o *orderController) expireHolds() {
+	select {
+	case <-o.done:
+		return
+	default:
+
+	}
+
+	err := o.model.ExpireHolds()
+	if err != nil {
+		o.errors <- errors.New("ошибка при освобождении просроченных резервов баллов: " + err.Error())
+	}
+
+	time.AfterFunc(time.Second*delayForExpiringHolds, func() { o.expireHolds() })
+}
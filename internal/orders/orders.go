@@ -1,65 +1,138 @@
 package orders
 
 import (
+	"context"
 	"errors"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/campaigns"
 	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/events"
+	"log"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 )
 
 const (
-	delayForGettingOrdersToProcess = 1
+	delayForGettingOrdersToProcess = 60
+	delayForRecheckingOrder        = 1
+	maxDelayForRecheckingOrder     = 5 * 60
+	delayForListeningNewOrders     = 5
+	maxDelayForListeningNewOrders  = 5 * 60
+	delayForExpiringPoints         = 60 * 60
+	delayForReleasingPoints        = 10 * 60
+	delayForExpiringHolds          = 60
+	delayForRecheckingProcessed    = 6 * 60 * 60
+	maxRecheckAttempts             = 10
+	defaultHoldTTL                 = 15 * time.Minute
+	maxHoldTTL                     = 24 * time.Hour
+	expiringPointsPeriod           = 30 * 24 * time.Hour
+	reversalWatchPeriod            = 30 * 24 * time.Hour
 	processChannelCount            = 10
 	ordersToSaveChannelSize        = 10
 	errorQueueSize                 = 10
-	orderStatusNew                 = "REGISTERED"
+	defaultPageSize                = 100
+	maxPageSize                    = 1000
+	MaxOrderStatusBatchSize        = 100
+	MaxOrderUploadBatchSize        = 1000
 )
 
 type Order struct {
-	ID         string
-	UserLogin  string
-	Status     string
-	UploadedAt time.Time
-	Amount     float32
+	ID             string
+	UserLogin      string
+	Status         string
+	PreviousStatus string
+	UploadedAt     time.Time
+	Amount         float32
+	Recheck        bool
+	Attempts       int
+	Multiplier     float32
 }
 
 type OrderAdderGetter interface {
 	AddOrder(user, order string) error
-	GetOrders(user string) ([]database.OrderWithAccrual, error)
+	AddOrders(user string, orderIDs []string) ([]database.OrderUploadResult, error)
+	GetOrders(user string, filter *database.OrderFilter) (*database.OrderPage, error)
+	GetOrder(user, orderID string) (*database.OrderDetails, error)
+	GetOrderStatuses(user string, orderIDs []string) ([]database.OrderWithAccrual, []string, error)
 	GetUserAccount(user string) (*database.Account, error)
-	WithdrawForOrder(user, orderID string, amount float32) error
-	GetUserWithdrawals(user string) ([]database.Transaction, error)
+	WithdrawForOrder(user, orderID, merchant string, amount, orderTotal float32) error
+	GetUserWithdrawals(user string) ([]database.Withdrawal, error)
+	RefundWithdrawal(orderID, merchant string, amount float32) (*database.Transaction, error)
+	GetUserTransactions(user string) ([]database.Transaction, error)
+	GetStatement(user string, filter *database.StatementFilter) (*database.StatementPage, error)
+	GetBalanceAt(user string, at *time.Time) (float32, error)
+	Transfer(user, recipient string, amount float32, note string) (*database.Transfer, error)
+	ConfirmTransfer(user string, transferID int64, passwordConfirmed bool) (*database.Transfer, error)
+	GetTransfers(user string) ([]database.Transfer, error)
+	CreateHold(user, orderID string, amount, orderTotal float32, ttl time.Duration) (*database.BalanceHold, error)
+	GetHolds(user string) ([]database.BalanceHold, error)
+	CaptureHold(user string, holdID int64) error
+	VoidHold(user string, holdID int64) error
+	GetUserTier(user string) (*TierInfo, error)
+	CheckReferralCode(code string) error
+	SetupNewUser(user, referralCode, ip string) error
+	GetReferralStats(user string) (*database.ReferralStats, error)
+	ChangeOrderStatus(orderID, status string) error
+	RecheckOrder(orderID string) error
+	GetUserOrderHistory(user, orderID string) ([]database.OrderStatusChange, error)
+	GetOrderHistory(orderID string) ([]database.OrderStatusChange, error)
 	Close()
 }
 
 type orderController struct {
 	accrualSystemAddress string
 
-	model  database.Storager
-	client http.Client
+	model            database.Storager
+	client           http.Client
+	tierPolicy       TierPolicy
+	bonusPolicy      BonusPolicy
+	transferPolicy   TransferPolicy
+	withdrawalPolicy WithdrawalPolicy
+	campaigns        campaigns.CampaignManager
+	events           events.Publisher
 
 	ordersToProcess    chan *Order
 	processingChannels []chan *Order
 	ordersToSave       chan *Order
 	errors             chan error
 	done               chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	trackedLock sync.Mutex
+	tracked     map[string]struct{}
 }
 
-func NewOrders(m database.Storager, accrualSystemAddress string) (OrderAdderGetter, error) {
+func NewOrders(m database.Storager, accrualSystemAddress string, tierPolicy TierPolicy, bonusPolicy BonusPolicy, transferPolicy TransferPolicy, withdrawalPolicy WithdrawalPolicy, c campaigns.CampaignManager, e events.Publisher) (OrderAdderGetter, error) {
 	if len(accrualSystemAddress) == 0 {
 		return nil, errors.New("не задан путь к серверу расчёта баллов лояльности")
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	result := orderController{
 		accrualSystemAddress: accrualSystemAddress,
 
 		ordersToProcess: make(chan *Order),
 		ordersToSave:    make(chan *Order, ordersToSaveChannelSize),
 		errors:          make(chan error, errorQueueSize),
+		done:            make(chan struct{}),
+
+		ctx:    ctx,
+		cancel: cancel,
+
+		tracked: make(map[string]struct{}),
 
-		model:  m,
-		client: http.Client{},
+		model:            m,
+		client:           http.Client{},
+		tierPolicy:       tierPolicy,
+		bonusPolicy:      bonusPolicy,
+		transferPolicy:   transferPolicy,
+		withdrawalPolicy: withdrawalPolicy,
+		campaigns:        c,
+		events:           e,
 	}
 
 	result.initOrderProcessing(processChannelCount)
@@ -86,7 +159,7 @@ func lunhChecksum(number int) int {
 	return luhh % 10
 }
 
-func (o *orderController) AddOrder(user, orderID string) error {
+func validateOrderNumber(user, orderID string) error {
 	orderNumber, err := strconv.Atoi(orderID)
 	if err != nil {
 		return NewOrderError(orderID, true, false, false, user, errors.New("номер заказа содержит символы, отличные от цифр"))
@@ -96,6 +169,15 @@ func (o *orderController) AddOrder(user, orderID string) error {
 		return NewOrderError(orderID, true, false, false, user, errors.New("контрольное число указано неправильно в номере заказа"))
 	}
 
+	return nil
+}
+
+func (o *orderController) AddOrder(user, orderID string) error {
+	err := validateOrderNumber(user, orderID)
+	if err != nil {
+		return err
+	}
+
 	var dbError *database.DBOrderError
 	err = o.model.AddOrder(user, orderID)
 	if err != nil && errors.As(err, &dbError) {
@@ -109,41 +191,135 @@ func (o *orderController) AddOrder(user, orderID string) error {
 	return nil
 }
 
-func (o *orderController) GetOrders(user string) ([]database.OrderWithAccrual, error) {
-	orders, err := o.model.GetOrders(user)
+func (o *orderController) AddOrders(user string, orderIDs []string) ([]database.OrderUploadResult, error) {
+	valid := make([]string, 0, len(orderIDs))
+	seen := make(map[string]struct{}, len(orderIDs))
+	for _, orderID := range orderIDs {
+		if _, ok := seen[orderID]; ok {
+			continue
+		}
+
+		seen[orderID] = struct{}{}
+		if validateOrderNumber(user, orderID) == nil {
+			valid = append(valid, orderID)
+		}
+	}
+
+	uploaded := make(map[string]string, len(valid))
+	if len(valid) > 0 {
+		results, err := o.model.AddOrders(user, valid)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			uploaded[result.OrderID] = result.Result
+		}
+	}
+
+	results := make([]database.OrderUploadResult, 0, len(orderIDs))
+	reported := make(map[string]struct{}, len(orderIDs))
+	for _, orderID := range orderIDs {
+		result, ok := uploaded[orderID]
+		if !ok {
+			result = database.OrderUploadInvalid
+		}
+
+		if _, ok := reported[orderID]; ok && result == database.OrderUploadAccepted {
+			result = database.OrderUploadDuplicate
+		}
+
+		reported[orderID] = struct{}{}
+		results = append(results, database.OrderUploadResult{OrderID: orderID, Result: result})
+	}
+
+	return results, nil
+}
+
+func (o *orderController) GetOrders(user string, filter *database.OrderFilter) (*database.OrderPage, error) {
+	if filter.Limit <= 0 && filter.After != nil {
+		filter.Limit = defaultPageSize
+	}
+
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+
+	page, err := o.model.GetOrders(user, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return orders, nil
+	return page, nil
 }
 
-func (o *orderController) GetUserAccount(user string) (*database.Account, error) {
-	account, err := o.model.GetUserAccount(user)
+func (o *orderController) GetOrder(user, orderID string) (*database.OrderDetails, error) {
+	order, err := o.model.GetUserOrder(user, orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	return account, nil
+	if order == nil {
+		return nil, NewOrderNotFoundError(orderID, user)
+	}
+
+	return order, nil
 }
 
-func (o *orderController) WithdrawForOrder(user string, orderID string, amount float32) error {
-	orderNumber, err := strconv.Atoi(orderID)
+func (o *orderController) GetOrderStatuses(user string, orderIDs []string) ([]database.OrderWithAccrual, []string, error) {
+	unique := make([]string, 0, len(orderIDs))
+	seen := make(map[string]struct{}, len(orderIDs))
+	for _, orderID := range orderIDs {
+		if _, ok := seen[orderID]; ok {
+			continue
+		}
+
+		seen[orderID] = struct{}{}
+		unique = append(unique, orderID)
+	}
+
+	found, err := o.model.GetOrderStatuses(user, unique)
 	if err != nil {
-		return NewOrderError(orderID, true, false, false, user, errors.New("номер заказа содержит символы, отличные от цифр"))
+		return nil, nil, err
 	}
 
-	if (orderNumber%10+lunhChecksum(orderNumber/10))%10 != 0 {
-		return NewOrderError(orderID, true, false, false, user, errors.New("контрольное число указано неправильно в номере заказа"))
+	for _, order := range found {
+		delete(seen, order.ID)
+	}
+
+	notFound := make([]string, 0, len(seen))
+	for _, orderID := range unique {
+		if _, ok := seen[orderID]; ok {
+			notFound = append(notFound, orderID)
+		}
+	}
+
+	return found, notFound, nil
+}
+
+func (o *orderController) GetUserAccount(user string) (*database.Account, error) {
+	account, err := o.model.GetUserAccount(user)
+	if err != nil {
+		return nil, err
+	}
+
+	account.Expiring, err = o.model.GetExpiringPoints(user, expiringPointsPeriod)
+	if err != nil {
+		return nil, err
 	}
 
-	account, err := o.GetUserAccount(user)
+	return account, nil
+}
+
+func (o *orderController) WithdrawForOrder(user, orderID, merchant string, amount, orderTotal float32) error {
+	err := validateOrderNumber(user, orderID)
 	if err != nil {
 		return err
 	}
 
-	if amount > account.Balance {
-		return NewOrderError(orderID, false, false, true, user, errors.New("на счёте пользователя "+user+" недостаточно средств ("+strconv.FormatFloat(float64(account.Balance), 'E', -1, 32)+") для списания "+strconv.FormatFloat(float64(amount), 'E', -1, 32)+" баллов"))
+	err = o.checkWithdrawalRules(user, orderID, amount, orderTotal)
+	if err != nil {
+		return err
 	}
 
 	transaction := database.Transaction{
@@ -152,29 +328,176 @@ func (o *orderController) WithdrawForOrder(user string, orderID string, amount f
 		Type:        database.TransactionTypeWithdrawal,
 		Amount:      amount,
 		CreatedAt:   database.CustomDateTime{Time: time.Now()},
+		MerchantID:  merchant,
+	}
+
+	var accountError *database.DBAccountError
+	err = o.model.Withdraw(&transaction, o.withdrawalLimits())
+	if err != nil && errors.As(err, &accountError) && accountError.InsufficientFunds {
+		return NewOrderError(orderID, false, false, true, user, err)
 	}
 
-	err = o.model.AddTransaction(&transaction)
+	if err != nil {
+		return withdrawalLimitError(orderID, user, amount, err)
+	}
+
+	o.publish(user, events.EventWithdrawalMade, events.WithdrawalMade{Order: orderID, Sum: amount})
+
+	return nil
+}
+
+func (o *orderController) GetUserWithdrawals(user string) ([]database.Withdrawal, error) {
+	withdrawals, err := o.model.GetWithdrawals(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return withdrawals, nil
+}
+
+func (o *orderController) RefundWithdrawal(orderID, merchant string, amount float32) (*database.Transaction, error) {
+	if amount < 0 {
+		return nil, NewOrderAmountError(orderID, "", errors.New("сумма возврата не может быть отрицательной"))
+	}
+
+	refund, err := o.model.Refund(orderID, merchant, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+func (o *orderController) GetUserTransactions(user string) ([]database.Transaction, error) {
+	transactions, err := o.model.GetUserTransactions(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+func (o *orderController) GetStatement(user string, filter *database.StatementFilter) (*database.StatementPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+
+	page, err := o.model.GetStatement(user, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (o *orderController) GetBalanceAt(user string, at *time.Time) (float32, error) {
+	return o.model.GetBalanceAt(user, at)
+}
+
+func (o *orderController) ChangeOrderStatus(orderID, status string) error {
+	order, err := o.model.GetOrder(orderID)
 	if err != nil {
 		return err
 	}
 
-	account.Balance -= amount
-	account.Withdrawn += amount
+	if order == nil {
+		return NewOrderNotFoundError(orderID, "")
+	}
+
+	err = checkStatusTransition(orderID, order.Status, status, database.OrderSourceAdmin)
+	if err != nil {
+		log.Println("Отклонено изменение статуса администратором:", err)
+		return err
+	}
+
+	previousStatus := order.Status
+	order.Status = status
+
+	var dbStatusError *database.DBOrderStatusError
+	err = o.model.UpdateOrder(order, previousStatus, 0, database.OrderSourceAdmin)
+	if err != nil && errors.As(err, &dbStatusError) {
+		return NewOrderStatusError(orderID, previousStatus, status, err)
+	}
 
-	err = o.model.UpdateUserAccount(account)
 	if err != nil {
 		return err
 	}
 
+	log.Println("Статус заказа " + orderID + " изменён администратором: " + previousStatus + " -> " + status)
+
+	o.publish(order.UserLogin, events.EventOrderStatusChanged, events.OrderStatusChanged{
+		Order:          orderID,
+		Status:         status,
+		PreviousStatus: previousStatus,
+	})
+
+	if status == OrderStatusNew {
+		o.enqueueOrder(&Order{
+			ID:         order.ID,
+			UserLogin:  order.UserLogin,
+			Status:     order.Status,
+			UploadedAt: order.UploadedAt,
+			Multiplier: order.Multiplier,
+		})
+	}
+
 	return nil
 }
 
-func (o *orderController) GetUserWithdrawals(user string) ([]database.Transaction, error) {
-	transactions, err := o.model.GetTransactions(user, database.TransactionTypeWithdrawal)
+func (o *orderController) GetUserOrderHistory(user, orderID string) ([]database.OrderStatusChange, error) {
+	order, err := o.model.GetOrder(orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	return transactions, nil
+	if order == nil || order.UserLogin != user {
+		return nil, NewOrderNotFoundError(orderID, user)
+	}
+
+	return o.model.GetOrderHistory(orderID)
+}
+
+func (o *orderController) GetOrderHistory(orderID string) ([]database.OrderStatusChange, error) {
+	order, err := o.model.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order == nil {
+		return nil, NewOrderNotFoundError(orderID, "")
+	}
+
+	return o.model.GetOrderHistory(orderID)
+}
+
+func (o *orderController) RecheckOrder(orderID string) error {
+	order, err := o.model.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+
+	if order == nil {
+		return NewOrderNotFoundError(orderID, "")
+	}
+
+	if order.Status != OrderStatusProcessed {
+		return NewOrderStatusError(orderID, order.Status, order.Status, errors.New("повторная проверка доступна только для заказов в статусе "+OrderStatusProcessed))
+	}
+
+	log.Println("Заказ " + orderID + " поставлен на повторную проверку в системе расчёта баллов")
+
+	o.enqueueOrder(&Order{
+		ID:         order.ID,
+		UserLogin:  order.UserLogin,
+		Status:     order.Status,
+		UploadedAt: order.UploadedAt,
+		Recheck:    true,
+		Multiplier: order.Multiplier,
+	})
+
+	return nil
 }
@@ -0,0 +1,57 @@
+package orders
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLunhChecksum(t *testing.T) {
+	tests := []struct {
+		number int
+		want   int
+	}{
+		{number: 0, want: 0},
+		{number: 7992739871, want: 7},
+		{number: 1234567890, want: 7},
+		{number: 9, want: 9},
+		{number: 5, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := lunhChecksum(tt.number); got != tt.want {
+			t.Errorf("lunhChecksum(%v) = %v, ожидалось %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestValidateOrderNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderID string
+		wantErr bool
+	}{
+		{name: "корректный номер", orderID: "79927398713"},
+		{name: "корректный номер с нулевым контрольным числом", orderID: "0"},
+		{name: "корректный длинный номер", orderID: "12345678903"},
+		{name: "неверное контрольное число", orderID: "79927398710", wantErr: true},
+		{name: "буквы в номере", orderID: "7992a398713", wantErr: true},
+		{name: "пустой номер", orderID: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOrderNumber("user", tt.orderID)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("validateOrderNumber(%v) вернул ошибку: %v", tt.orderID, err)
+				}
+				return
+			}
+
+			var orderError *OrderError
+			if !errors.As(err, &orderError) || !orderError.IncorrectID {
+				t.Errorf("validateOrderNumber(%v) = %v, ожидалась ошибка неверного номера", tt.orderID, err)
+			}
+		})
+	}
+}
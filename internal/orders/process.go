@@ -27,7 +27,13 @@ func (o *orderController) initOrderProcessing(channelCount int) {
 
 	go o.processErrors()
 	go o.getOrdersToProcess()
+	go o.listenNewOrders()
 	go o.processOrdersToSave()
+	go o.expirePoints()
+	go o.releasePendingPoints()
+	go o.recheckProcessedOrders()
+	go o.expireHolds()
+	go o.evaluateTiers()
 
 	go func() {
 		defer o.closeProcessingChannels()
@@ -37,9 +43,11 @@ func (o *orderController) initOrderProcessing(channelCount int) {
 				i = 0
 			}
 
-			order, ok := <-o.ordersToProcess
-			if !ok {
+			var order *Order
+			select {
+			case <-o.done:
 				return
+			case order = <-o.ordersToProcess:
 			}
 
 			ch := o.processingChannels[i]
@@ -73,12 +81,171 @@ func (o *orderController) getOrdersToProcess() {
 			UploadedAt: order.UploadedAt,
 		}
 
-		o.ordersToProcess <- &orderToProcess
+		o.enqueueOrder(&orderToProcess)
 	}
 
 	time.AfterFunc(time.Second*delayForGettingOrdersToProcess, func() { o.getOrdersToProcess() })
 }
 
+func (o *orderController) expirePoints() {
+	select {
+	case <-o.done:
+		return
+	default:
+
+	}
+
+	err := o.model.ExpirePoints()
+	if err != nil {
+		o.errors <- errors.New("ошибка при сгорании баллов: " + err.Error())
+	}
+
+	time.AfterFunc(time.Second*delayForExpiringPoints, func() { o.expirePoints() })
+}
+
+func (o *orderController) releasePendingPoints() {
+	select {
+	case <-o.done:
+		return
+	default:
+
+	}
+
+	err := o.model.ReleasePendingPoints()
+	if err != nil {
+		o.errors <- errors.New("ошибка при зачислении ожидающих баллов: " + err.Error())
+	}
+
+	time.AfterFunc(time.Second*delayForReleasingPoints, func() { o.releasePendingPoints() })
+}
+
+func (o *orderController) recheckProcessedOrders() {
+	select {
+	case <-o.done:
+		return
+	default:
+
+	}
+
+	orders, err := o.model.GetOrdersToRecheck(time.Now().Add(-reversalWatchPeriod))
+	if err != nil {
+		o.errors <- errors.New("ошибка при получении заказов для повторной проверки: " + err.Error())
+		time.AfterFunc(time.Second*delayForRecheckingProcessed, func() { o.recheckProcessedOrders() })
+		return
+	}
+
+	log.Printf("Найдено %v заказов для повторной проверки\n", len(orders))
+
+	for _, order := range orders {
+		o.enqueueOrder(&Order{
+			ID:         order.ID,
+			UserLogin:  order.UserLogin,
+			Status:     order.Status,
+			UploadedAt: order.UploadedAt,
+			Recheck:    true,
+			Multiplier: order.Multiplier,
+		})
+	}
+
+	time.AfterFunc(time.Second*delayForRecheckingProcessed, func() { o.recheckProcessedOrders() })
+}
+
+func (o *orderController) listenNewOrders() {
+	delay := time.Second * delayForListeningNewOrders
+
+	for {
+		order, err := o.model.WaitForNewOrder(o.ctx)
+
+		select {
+		case <-o.done:
+			return
+		default:
+
+		}
+
+		if err != nil {
+			o.errors <- errors.New("ошибка при ожидании уведомления о новом заказе, повтор через " + delay.String() + ": " + err.Error())
+
+			select {
+			case <-o.done:
+				return
+			case <-time.After(delay):
+			}
+
+			delay = backoff(delay, time.Second*maxDelayForListeningNewOrders)
+			continue
+		}
+
+		delay = time.Second * delayForListeningNewOrders
+
+		o.enqueueOrder(&Order{
+			ID:         order.ID,
+			UserLogin:  order.UserLogin,
+			Status:     order.Status,
+			UploadedAt: order.UploadedAt,
+		})
+	}
+}
+
+func (o *orderController) enqueueOrder(order *Order) {
+	o.trackedLock.Lock()
+	_, found := o.tracked[order.ID]
+	if !found {
+		o.tracked[order.ID] = struct{}{}
+	}
+	o.trackedLock.Unlock()
+
+	if found {
+		log.Println("Заказ " + order.ID + " уже находится в обработке")
+		return
+	}
+
+	o.sendToProcess(order)
+}
+
+func (o *orderController) sendToProcess(order *Order) {
+	select {
+	case <-o.done:
+	case o.ordersToProcess <- order:
+	}
+}
+
+func (o *orderController) recheckLater(order *Order, delay time.Duration) {
+	if order.Recheck && order.Attempts >= maxRecheckAttempts {
+		log.Printf("Повторная проверка заказа '%v' прекращена после %v попыток, статус '%v' сохранён\n", order.ID, order.Attempts, order.Status)
+		o.untrack(order.ID)
+		return
+	}
+
+	order.Attempts++
+	time.AfterFunc(delay, func() { o.sendToProcess(order) })
+}
+
+func nextRecheckDelay(attempts int) time.Duration {
+	delay := time.Second * delayForRecheckingOrder
+	for i := 0; i < attempts && delay < time.Second*maxDelayForRecheckingOrder; i++ {
+		delay = backoff(delay, time.Second*maxDelayForRecheckingOrder)
+	}
+
+	return delay
+}
+
+func backoff(delay, limit time.Duration) time.Duration {
+	delay *= 2
+	if delay > limit {
+		return limit
+	}
+
+	return delay
+}
+
+func (o *orderController) untrack(orderID string) {
+	o.trackedLock.Lock()
+	defer o.trackedLock.Unlock()
+
+	delete(o.tracked, orderID)
+}
+
 func (o *orderController) processOrdersInChannel(processingChannel <-chan *Order) {
 	for order := range processingChannel {
 		o.processOrder(order)
@@ -99,16 +266,16 @@ func (o *orderController) closeProcessingChannels() {
 
 func (o *orderController) Close() {
 	close(o.done)
-	close(o.ordersToProcess)
-	close(o.ordersToSave)
-	close(o.errors)
+	o.cancel()
 }
 
 func (o *orderController) processOrder(order *Order) {
+	recheckDelay := nextRecheckDelay(order.Attempts)
 
 	response, err := o.client.Get(o.accrualSystemAddress + "/api/orders/" + order.ID)
 	if err != nil {
 		o.errors <- err
+		o.recheckLater(order, recheckDelay)
 		return
 	}
 
@@ -119,6 +286,7 @@ func (o *orderController) processOrder(order *Order) {
 
 	case 204:
 		o.errors <- errors.New("заказ " + order.ID + " не зарегистрирован в системе")
+		o.recheckLater(order, recheckDelay)
 		return
 
 	case 429:
@@ -127,32 +295,39 @@ func (o *orderController) processOrder(order *Order) {
 		retryAfter, err := strconv.Atoi(retry)
 		if err != nil {
 			o.errors <- errors.New("превышено количество запросов к сервису: " + response.Status + ", некорректный заголовок Retry-After: " + retry)
+			o.recheckLater(order, recheckDelay)
 			return
 		}
 
+		recheckDelay = time.Second * time.Duration(retryAfter)
+		o.recheckLater(order, recheckDelay)
+
 		body, err := io.ReadAll(response.Body)
 		if err != nil {
-			o.postponeProcessing(time.Second * time.Duration(retryAfter))
+			o.postponeProcessing(recheckDelay)
 			o.errors <- errors.New("превышено количество запросов к сервису: " + response.Status + ". " + err.Error())
 			return
 		}
 
-		o.postponeProcessing(time.Second * time.Duration(retryAfter))
+		o.postponeProcessing(recheckDelay)
 		o.errors <- errors.New("превышено количество запросов к сервису: " + response.Status + ". " + string(body))
 		return
 
 	case 500:
 		o.errors <- errors.New("ошибка сервера рассчёта баллов лояльности")
+		o.recheckLater(order, recheckDelay)
 		return
 
 	default:
 		o.errors <- errors.New("неизвестная ошибка, код " + strconv.Itoa(response.StatusCode) + ", описание: " + response.Status)
+		o.recheckLater(order, recheckDelay)
 		return
 	}
 
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
 		o.errors <- err
+		o.recheckLater(order, recheckDelay)
 		return
 	}
 
@@ -160,24 +335,64 @@ func (o *orderController) processOrder(order *Order) {
 	err = json.Unmarshal(body, &orderBonuses)
 	if err != nil {
 		o.errors <- err
+		o.recheckLater(order, recheckDelay)
 		return
 	}
 
 	o.errors <- errors.New("Получен статус " + orderBonuses.Status + " по заказу " + orderBonuses.ID + ". Кол-во начисленных бонусов: " + strconv.FormatFloat(float64(orderBonuses.BonusAmount), 'E', -1, 32) + ".")
-	if orderBonuses.Status != orderStatusNew {
-		orderToSave := &Order{
-			ID:         orderBonuses.ID,
-			UserLogin:  order.UserLogin,
-			Status:     orderBonuses.Status,
-			UploadedAt: order.UploadedAt,
-			Amount:     orderBonuses.BonusAmount,
+
+	status, err := orderStatusFromAccrual(order.ID, orderBonuses.Status)
+	if err != nil {
+		o.errors <- err
+		o.recheckLater(order, recheckDelay)
+		return
+	}
+
+	source := database.OrderSourceAccrual
+	if order.Recheck {
+		source = database.OrderSourceRecheck
+	}
+
+	if !isFinalOrderStatus(status) && (order.Recheck || status == order.Status) {
+		o.recheckLater(order, recheckDelay)
+		return
+	}
+
+	err = checkStatusTransition(order.ID, order.Status, status, source)
+	if err != nil {
+		o.errors <- errors.New("отклонён ответ системы расчёта баллов: " + err.Error())
+
+		if isFinalOrderStatus(order.Status) {
+			o.untrack(order.ID)
+			return
 		}
-		o.ordersToSave <- orderToSave
+
+		o.recheckLater(order, recheckDelay)
+		return
+	}
+
+	orderToSave := &Order{
+		ID:             order.ID,
+		UserLogin:      order.UserLogin,
+		Status:         status,
+		PreviousStatus: order.Status,
+		UploadedAt:     order.UploadedAt,
+		Amount:         orderBonuses.BonusAmount,
+		Recheck:        order.Recheck,
+		Multiplier:     order.Multiplier,
 	}
+	o.ordersToSave <- orderToSave
 }
 
 func (o *orderController) processOrdersToSave() {
-	for orderToSave := range o.ordersToSave {
+	for {
+		var orderToSave *Order
+		select {
+		case <-o.done:
+			return
+		case orderToSave = <-o.ordersToSave:
+		}
+
 		order := database.Order{
 			ID:         orderToSave.ID,
 			UserLogin:  orderToSave.UserLogin,
@@ -185,10 +400,51 @@ func (o *orderController) processOrdersToSave() {
 			UploadedAt: orderToSave.UploadedAt,
 		}
 
-		err := o.model.UpdateOrder(&order, orderToSave.Amount)
+		source := database.OrderSourceAccrual
+		if orderToSave.Recheck {
+			source = database.OrderSourceRecheck
+		}
+
+		amount := orderToSave.Amount
+		if orderToSave.Status == OrderStatusProcessed && amount > 0 {
+			multiplier := orderToSave.Multiplier
+			if multiplier <= 0 {
+				var err error
+				multiplier, err = o.getAccrualMultiplier(orderToSave.UserLogin)
+				if err != nil {
+					o.errors <- err
+				}
+			}
+
+			order.Multiplier = multiplier
+			amount *= multiplier
+		}
+
+		err := o.model.UpdateOrder(&order, orderToSave.PreviousStatus, amount, source)
 		if err != nil {
 			o.errors <- err
+			o.untrack(orderToSave.ID)
+			continue
+		}
+
+		o.publishOrderSaved(orderToSave, amount)
+
+		if orderToSave.Status == OrderStatusProcessed && !orderToSave.Recheck {
+			o.rewardReferral(orderToSave)
+			o.applyCampaigns(orderToSave)
+		}
+
+		if orderToSave.Status == OrderStatusProcessing {
+			o.recheckLater(&Order{
+				ID:         orderToSave.ID,
+				UserLogin:  orderToSave.UserLogin,
+				Status:     orderToSave.Status,
+				UploadedAt: orderToSave.UploadedAt,
+			}, time.Second*delayForRecheckingOrder)
+			continue
 		}
+
+		o.untrack(orderToSave.ID)
 	}
 }
 
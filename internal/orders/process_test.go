@@ -0,0 +1,68 @@
+package orders
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextRecheckDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Second * delayForRecheckingOrder},
+		{attempts: 1, want: 2 * time.Second * delayForRecheckingOrder},
+		{attempts: 3, want: 8 * time.Second * delayForRecheckingOrder},
+		{attempts: 100, want: time.Second * maxDelayForRecheckingOrder},
+	}
+
+	for _, tt := range tests {
+		if got := nextRecheckDelay(tt.attempts); got != tt.want {
+			t.Errorf("nextRecheckDelay(%v) = %v, ожидалось %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRecheckLaterStopsRechecks(t *testing.T) {
+	tests := []struct {
+		name         string
+		order        Order
+		wantAttempts int
+		wantTracked  bool
+	}{
+		{
+			name:         "повторная проверка в пределах лимита",
+			order:        Order{ID: "12345678903", Recheck: true, Attempts: maxRecheckAttempts - 1},
+			wantAttempts: maxRecheckAttempts,
+			wantTracked:  true,
+		},
+		{
+			name:         "повторная проверка исчерпала попытки",
+			order:        Order{ID: "12345678903", Recheck: true, Attempts: maxRecheckAttempts},
+			wantAttempts: maxRecheckAttempts,
+			wantTracked:  false,
+		},
+		{
+			name:         "обычная обработка не ограничена",
+			order:        Order{ID: "12345678903", Attempts: maxRecheckAttempts},
+			wantAttempts: maxRecheckAttempts + 1,
+			wantTracked:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &orderController{tracked: map[string]struct{}{tt.order.ID: {}}}
+
+			o.recheckLater(&tt.order, time.Hour)
+
+			if tt.order.Attempts != tt.wantAttempts {
+				t.Errorf("попыток = %v, ожидалось %v", tt.order.Attempts, tt.wantAttempts)
+			}
+
+			if _, tracked := o.tracked[tt.order.ID]; tracked != tt.wantTracked {
+				t.Errorf("заказ отслеживается = %v, ожидалось %v", tracked, tt.wantTracked)
+			}
+		})
+	}
+}
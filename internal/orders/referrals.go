@@ -0,0 +1,147 @@
+package orders
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	referralCodeLength   = 8
+	referralCodeAttempts = 5
+	referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+type BonusPolicy struct {
+	WelcomeBonus  float32
+	ReferralBonus float32
+	ReferralLimit int
+}
+
+func newReferralCode() (string, error) {
+	code := make([]byte, referralCodeLength)
+	max := big.NewInt(int64(len(referralCodeAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		code[i] = referralCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+func (o *orderController) CheckReferralCode(code string) error {
+	_, err := o.model.GetUserByReferralCode(code)
+	if err != nil {
+		return o.referralError(code, err)
+	}
+
+	return nil
+}
+
+func (o *orderController) referralError(code string, err error) error {
+	var referralError *database.DBReferralError
+	if errors.As(err, &referralError) && referralError.NotFound {
+		return NewReferralError(code, true, err)
+	}
+
+	return err
+}
+
+func (o *orderController) SetupNewUser(user, referralCode, ip string) error {
+	referrer := ""
+
+	if referralCode != "" {
+		var err error
+		referrer, err = o.model.GetUserByReferralCode(referralCode)
+		if err != nil {
+			return o.referralError(referralCode, err)
+		}
+
+		self, err := o.model.IsSelfReferral(user, referrer, ip)
+		if err != nil {
+			return err
+		}
+
+		if self {
+			log.Printf("Приглашение пользователя '%v' пользователем '%v' отклонено: обнаружена регистрация самого себя\n", user, referrer)
+			referrer = ""
+		}
+	}
+
+	err := o.assignReferralCode(func(code string) error {
+		return o.model.SetUserReferral(user, code, referrer, ip)
+	})
+	if err != nil {
+		return err
+	}
+
+	if o.bonusPolicy.WelcomeBonus <= 0 {
+		return nil
+	}
+
+	return o.model.AddWelcomeBonus(user, o.bonusPolicy.WelcomeBonus)
+}
+
+func (o *orderController) assignReferralCode(save func(code string) error) error {
+	var referralError *database.DBReferralError
+	var err error
+
+	for i := 0; i < referralCodeAttempts; i++ {
+		var code string
+		code, err = newReferralCode()
+		if err != nil {
+			return err
+		}
+
+		err = save(code)
+		if err == nil || !errors.As(err, &referralError) {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (o *orderController) GetReferralStats(user string) (*database.ReferralStats, error) {
+	stats, err := o.model.GetReferralStats(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Code != "" {
+		return stats, nil
+	}
+
+	err = o.assignReferralCode(func(code string) error {
+		return o.model.SetReferralCode(user, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return o.model.GetReferralStats(user)
+}
+
+func (o *orderController) rewardReferral(order *Order) {
+	if o.bonusPolicy.ReferralBonus <= 0 {
+		return
+	}
+
+	referrer, err := o.model.RewardReferral(order.UserLogin, order.ID, o.bonusPolicy.ReferralBonus, o.bonusPolicy.ReferralLimit)
+	if err != nil {
+		o.errors <- errors.New("ошибка при начислении бонуса за приглашение пользователя " + order.UserLogin + ": " + err.Error())
+		return
+	}
+
+	if referrer != "" {
+		log.Printf("Пользователю '%v' начислен бонус за приглашение '%v'\n", referrer, order.UserLogin)
+	}
+}
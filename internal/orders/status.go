@@ -0,0 +1,76 @@
+package orders
+
+import (
+	"errors"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	OrderStatusNew        = "NEW"
+	OrderStatusProcessing = "PROCESSING"
+	OrderStatusProcessed  = "PROCESSED"
+	OrderStatusInvalid    = "INVALID"
+
+	accrualStatusRegistered = "REGISTERED"
+	accrualStatusProcessing = "PROCESSING"
+	accrualStatusProcessed  = "PROCESSED"
+	accrualStatusInvalid    = "INVALID"
+)
+
+var orderStatusTransitions = map[string][]string{
+	OrderStatusNew:        {OrderStatusProcessing, OrderStatusProcessed, OrderStatusInvalid},
+	OrderStatusProcessing: {OrderStatusProcessed, OrderStatusInvalid},
+}
+
+var sourceOrderStatusTransitions = map[string]map[string][]string{
+	database.OrderSourceAdmin: {
+		OrderStatusProcessing: {OrderStatusNew},
+		OrderStatusProcessed:  {OrderStatusInvalid},
+		OrderStatusInvalid:    {OrderStatusNew},
+	},
+	database.OrderSourceRecheck: {
+		OrderStatusProcessed: {OrderStatusProcessed, OrderStatusInvalid},
+	},
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isFinalOrderStatus(status string) bool {
+	return status == OrderStatusProcessed || status == OrderStatusInvalid
+}
+
+func checkStatusTransition(orderID, from, to, source string) error {
+	if containsStatus(orderStatusTransitions[from], to) {
+		return nil
+	}
+
+	if containsStatus(sourceOrderStatusTransitions[source][from], to) {
+		return nil
+	}
+
+	return NewOrderStatusError(orderID, from, to, errors.New("переход заказа "+orderID+" из статуса "+from+" в статус "+to+" запрещён для источника "+source))
+}
+
+func orderStatusFromAccrual(orderID, accrualStatus string) (string, error) {
+	switch accrualStatus {
+	case accrualStatusRegistered:
+		return OrderStatusNew, nil
+	case accrualStatusProcessing:
+		return OrderStatusProcessing, nil
+	case accrualStatusProcessed:
+		return OrderStatusProcessed, nil
+	case accrualStatusInvalid:
+		return OrderStatusInvalid, nil
+	}
+
+	return "", errors.New("получен неизвестный статус " + accrualStatus + " по заказу " + orderID)
+}
@@ -0,0 +1,259 @@
+package orders
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	TierBasisLifetime = "lifetime"
+	TierBasisRolling  = "rolling12m"
+
+	rollingTierPeriodMonths = 12
+)
+
+type Tier struct {
+	Name       string
+	Threshold  float32
+	Multiplier float32
+}
+
+type TierPolicy struct {
+	Tiers []Tier
+	Basis string
+}
+
+type TierInfo struct {
+	Tier          string                   `json:"tier"`
+	Multiplier    float32                  `json:"multiplier"`
+	Basis         string                   `json:"basis"`
+	Accrued       float32                  `json:"accrued"`
+	NextTier      string                   `json:"next_tier,omitempty"`
+	NextThreshold float32                  `json:"next_threshold,omitempty"`
+	Remaining     float32                  `json:"remaining,omitempty"`
+	Progress      float32                  `json:"progress"`
+	EvaluatedAt   *database.CustomDateTime `json:"evaluated_at,omitempty"`
+}
+
+func ParseTiers(s string) ([]Tier, error) {
+	tiers := make([]Tier, 0)
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			return nil, errors.New("неверный формат уровня лояльности '" + item + "', ожидается имя:порог:множитель")
+		}
+
+		threshold, err := strconv.ParseFloat(parts[1], 32)
+		if err != nil {
+			return nil, errors.New("неверный порог уровня лояльности '" + item + "': " + err.Error())
+		}
+
+		multiplier, err := strconv.ParseFloat(parts[2], 32)
+		if err != nil {
+			return nil, errors.New("неверный множитель уровня лояльности '" + item + "': " + err.Error())
+		}
+
+		if multiplier <= 0 {
+			return nil, errors.New("множитель уровня лояльности '" + item + "' должен быть больше нуля")
+		}
+
+		tiers = append(tiers, Tier{
+			Name:       parts[0],
+			Threshold:  float32(threshold),
+			Multiplier: float32(multiplier),
+		})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].Threshold < tiers[j].Threshold })
+
+	return tiers, nil
+}
+
+func (p *TierPolicy) since(now time.Time) time.Time {
+	if p.Basis == TierBasisLifetime {
+		return time.Time{}
+	}
+
+	return now.AddDate(0, -rollingTierPeriodMonths, 0)
+}
+
+func (p *TierPolicy) tierFor(accrued float32) int {
+	index := -1
+	for i, tier := range p.Tiers {
+		if accrued >= tier.Threshold {
+			index = i
+		}
+	}
+
+	return index
+}
+
+func (p *TierPolicy) tierIndex(name string) int {
+	for i, tier := range p.Tiers {
+		if tier.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (o *orderController) scheduleTierEvaluation() {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+
+	time.AfterFunc(midnight.Sub(now), func() { o.evaluateTiers() })
+}
+
+func (o *orderController) evaluateTiers() {
+	select {
+	case <-o.done:
+		return
+	default:
+
+	}
+
+	defer o.scheduleTierEvaluation()
+
+	if len(o.tierPolicy.Tiers) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	totals, err := o.model.GetAccrualTotals(o.tierPolicy.since(now))
+	if err != nil {
+		o.errors <- errors.New("ошибка при пересчёте уровней лояльности: " + err.Error())
+		return
+	}
+
+	userTiers := make([]database.UserTier, 0, len(totals))
+	for user, accrued := range totals {
+		index := o.tierPolicy.tierFor(accrued)
+		if index < 0 {
+			continue
+		}
+
+		userTiers = append(userTiers, database.UserTier{
+			UserLogin:   user,
+			Tier:        o.tierPolicy.Tiers[index].Name,
+			Accrued:     accrued,
+			EvaluatedAt: now,
+		})
+	}
+
+	err = o.model.SaveUserTiers(userTiers, now)
+	if err != nil {
+		o.errors <- errors.New("ошибка при сохранении уровней лояльности: " + err.Error())
+		return
+	}
+
+	log.Printf("Пересчитаны уровни лояльности для %v пользователей\n", len(userTiers))
+}
+
+func (o *orderController) getUserTierIndex(user string) (int, *database.UserTier, error) {
+	if len(o.tierPolicy.Tiers) == 0 {
+		return -1, nil, nil
+	}
+
+	userTier, err := o.model.GetUserTier(user)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	if userTier == nil {
+		return o.tierPolicy.tierFor(0), nil, nil
+	}
+
+	return o.tierPolicy.tierIndex(userTier.Tier), userTier, nil
+}
+
+func (o *orderController) getAccrualMultiplier(user string) (float32, error) {
+	index, _, err := o.getUserTierIndex(user)
+	if err != nil {
+		return 1, err
+	}
+
+	if index < 0 {
+		return 1, nil
+	}
+
+	return o.tierPolicy.Tiers[index].Multiplier, nil
+}
+
+func (o *orderController) GetUserTier(user string) (*TierInfo, error) {
+	index, userTier, err := o.getUserTierIndex(user)
+	if err != nil {
+		return nil, err
+	}
+
+	accrued, err := o.model.GetUserAccrualTotal(user, o.tierPolicy.since(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	info := TierInfo{
+		Multiplier: 1,
+		Basis:      o.tierPolicy.Basis,
+		Accrued:    accrued,
+		Progress:   1,
+	}
+
+	if userTier != nil {
+		info.EvaluatedAt = &database.CustomDateTime{Time: userTier.EvaluatedAt}
+	}
+
+	var currentThreshold float32
+	if index >= 0 {
+		info.Tier = o.tierPolicy.Tiers[index].Name
+		info.Multiplier = o.tierPolicy.Tiers[index].Multiplier
+		currentThreshold = o.tierPolicy.Tiers[index].Threshold
+	}
+
+	if index+1 < len(o.tierPolicy.Tiers) {
+		next := o.tierPolicy.Tiers[index+1]
+
+		info.NextTier = next.Name
+		info.NextThreshold = next.Threshold
+		info.Progress = 0
+
+		if accrued < next.Threshold {
+			info.Remaining = next.Threshold - accrued
+		}
+
+		if next.Threshold > currentThreshold {
+			info.Progress = (accrued - currentThreshold) / (next.Threshold - currentThreshold)
+		}
+
+		if info.Progress < 0 {
+			info.Progress = 0
+		}
+
+		if info.Progress > 1 {
+			info.Progress = 1
+		}
+	}
+
+	return &info, nil
+}
+
+func (o *orderController) getUserTierName(user string) (string, error) {
+	index, _, err := o.getUserTierIndex(user)
+	if err != nil || index < 0 {
+		return "", err
+	}
+
+	return o.tierPolicy.Tiers[index].Name, nil
+}
@@ -0,0 +1,100 @@
+package orders
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []Tier
+		wantErr bool
+	}{
+		{
+			name:  "уровни сортируются по порогу",
+			value: "gold:1000:1.5, silver:500:1.2,bronze:0:1",
+			want: []Tier{
+				{Name: "bronze", Threshold: 0, Multiplier: 1},
+				{Name: "silver", Threshold: 500, Multiplier: 1.2},
+				{Name: "gold", Threshold: 1000, Multiplier: 1.5},
+			},
+		},
+		{name: "пустая строка", value: "", want: []Tier{}},
+		{name: "пустые элементы пропускаются", value: " ,base:0:1,", want: []Tier{{Name: "base", Threshold: 0, Multiplier: 1}}},
+		{name: "не хватает частей", value: "gold:1000", wantErr: true},
+		{name: "неверный порог", value: "gold:много:1.5", wantErr: true},
+		{name: "неверный множитель", value: "gold:1000:x", wantErr: true},
+		{name: "нулевой множитель", value: "gold:1000:0", wantErr: true},
+		{name: "отрицательный множитель", value: "gold:1000:-1.5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTiers(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseTiers(%q) не вернул ошибку", tt.value)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseTiers(%q) вернул ошибку: %v", tt.value, err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTiers(%q) = %v, ожидалось %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTierPolicyTierFor(t *testing.T) {
+	policy := TierPolicy{Tiers: []Tier{
+		{Name: "silver", Threshold: 100, Multiplier: 1.2},
+		{Name: "gold", Threshold: 500, Multiplier: 1.5},
+	}}
+
+	tests := []struct {
+		accrued float32
+		want    int
+	}{
+		{accrued: 0, want: -1},
+		{accrued: 99.99, want: -1},
+		{accrued: 100, want: 0},
+		{accrued: 499, want: 0},
+		{accrued: 500, want: 1},
+		{accrued: 10000, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := policy.tierFor(tt.accrued); got != tt.want {
+			t.Errorf("tierFor(%v) = %v, ожидалось %v", tt.accrued, got, tt.want)
+		}
+	}
+}
+
+func TestTierPolicyTierIndex(t *testing.T) {
+	policy := TierPolicy{Tiers: []Tier{
+		{Name: "silver", Threshold: 100, Multiplier: 1.2},
+		{Name: "gold", Threshold: 500, Multiplier: 1.5},
+	}}
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{name: "silver", want: 0},
+		{name: "gold", want: 1},
+		{name: "platinum", want: -1},
+		{name: "", want: -1},
+	}
+
+	for _, tt := range tests {
+		if got := policy.tierIndex(tt.name); got != tt.want {
+			t.Errorf("tierIndex(%q) = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package orders
+
+import (
+	"errors"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/events"
+)
+
+const (
+	transferConfirmationTTL = 10 * time.Minute
+	maxTransferNoteLength   = 200
+)
+
+type TransferPolicy struct {
+	DailyLimit            float32
+	ConfirmationThreshold float32
+}
+
+func (o *orderController) Transfer(user, recipient string, amount float32, note string) (*database.Transfer, error) {
+	if recipient == "" || recipient == user {
+		return nil, NewTransferError(recipient, user, true, errors.New("неверно указан получатель перевода"))
+	}
+
+	if amount <= 0 {
+		return nil, NewOrderAmountError("", user, errors.New("сумма перевода должна быть больше нуля"))
+	}
+
+	if utf8.RuneCountInString(note) > maxTransferNoteLength {
+		return nil, NewTransferError(recipient, user, false, errors.New("комментарий к переводу слишком длинный"))
+	}
+
+	now := time.Now()
+	transfer := database.Transfer{
+		Sender:    user,
+		Recipient: recipient,
+		Amount:    amount,
+		Note:      note,
+		Status:    database.TransferStatusCompleted,
+		CreatedAt: database.CustomDateTime{Time: now},
+	}
+
+	if o.transferPolicy.ConfirmationThreshold > 0 && amount >= o.transferPolicy.ConfirmationThreshold {
+		transfer.Status = database.TransferStatusPending
+		transfer.ExpiresAt = &database.CustomDateTime{Time: now.Add(transferConfirmationTTL)}
+	}
+
+	var accountError *database.DBAccountError
+	err := o.model.CreateTransfer(&transfer, o.transferPolicy.DailyLimit)
+	if err != nil && errors.As(err, &accountError) && accountError.InsufficientFunds {
+		return nil, NewOrderError("", false, false, true, user, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if transfer.Status == database.TransferStatusPending {
+		o.publish(user, events.EventTransferConfirmationRequested, events.TransferConfirmationRequested{
+			TransferID: transfer.ID,
+			ExpiresAt:  transfer.ExpiresAt.Time,
+		})
+	}
+
+	log.Printf("Создан перевод '%v' от пользователя '%v' пользователю '%v' в статусе '%v'\n", transfer.ID, user, recipient, transfer.Status)
+	return &transfer, nil
+}
+
+func (o *orderController) ConfirmTransfer(user string, transferID int64, passwordConfirmed bool) (*database.Transfer, error) {
+	var accountError *database.DBAccountError
+	transfer, err := o.model.ConfirmTransfer(user, transferID, passwordConfirmed, o.transferPolicy.DailyLimit)
+	if err != nil && errors.As(err, &accountError) && accountError.InsufficientFunds {
+		return nil, NewOrderError("", false, false, true, user, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Подтверждён перевод '%v' от пользователя '%v' пользователю '%v'\n", transfer.ID, user, transfer.Recipient)
+	return transfer, nil
+}
+
+func (o *orderController) GetTransfers(user string) ([]database.Transfer, error) {
+	transfers, err := o.model.GetTransfers(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
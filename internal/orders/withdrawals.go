@@ -0,0 +1,74 @@
+package orders
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	WithdrawalRulePositiveAmount = "positive_amount"
+	WithdrawalRuleMinAmount      = "min_amount"
+	WithdrawalRuleMaxOrderShare  = "max_order_share"
+	WithdrawalRuleDailyLimit     = "daily_limit"
+	WithdrawalRuleMonthlyLimit   = "monthly_limit"
+)
+
+type WithdrawalPolicy struct {
+	MinAmount     float32
+	MaxOrderShare float32
+	DailyLimit    float32
+	MonthlyLimit  float32
+}
+
+func formatAmount(amount float32) string {
+	return strconv.FormatFloat(float64(amount), 'f', -1, 32)
+}
+
+func (o *orderController) checkWithdrawalRules(user, orderID string, amount, orderTotal float32) error {
+	if amount <= 0 {
+		return NewWithdrawalRuleError(orderID, user, WithdrawalRulePositiveAmount, 0, amount,
+			errors.New("сумма списания должна быть больше нуля"))
+	}
+
+	policy := o.withdrawalPolicy
+
+	if policy.MinAmount > 0 && amount < policy.MinAmount {
+		return NewWithdrawalRuleError(orderID, user, WithdrawalRuleMinAmount, policy.MinAmount, amount,
+			errors.New("сумма списания меньше минимальной ("+formatAmount(policy.MinAmount)+" баллов)"))
+	}
+
+	if policy.MaxOrderShare > 0 && orderTotal <= 0 {
+		return NewWithdrawalRuleError(orderID, user, WithdrawalRuleMaxOrderShare, 0, amount,
+			errors.New("не указана стоимость заказа, необходимая для проверки доли оплаты баллами"))
+	}
+
+	if policy.MaxOrderShare > 0 && amount > orderTotal*policy.MaxOrderShare {
+		return NewWithdrawalRuleError(orderID, user, WithdrawalRuleMaxOrderShare, orderTotal*policy.MaxOrderShare, amount,
+			errors.New("баллами можно оплатить не более "+formatAmount(policy.MaxOrderShare*100)+"% стоимости заказа"))
+	}
+
+	return nil
+}
+
+func (o *orderController) withdrawalLimits() database.WithdrawalLimits {
+	return database.WithdrawalLimits{
+		Daily:   o.withdrawalPolicy.DailyLimit,
+		Monthly: o.withdrawalPolicy.MonthlyLimit,
+	}
+}
+
+func withdrawalLimitError(orderID, user string, amount float32, err error) error {
+	var limitError *database.DBWithdrawalLimitError
+	if !errors.As(err, &limitError) {
+		return err
+	}
+
+	rule := WithdrawalRuleDailyLimit
+	if limitError.Monthly {
+		rule = WithdrawalRuleMonthlyLimit
+	}
+
+	return NewWithdrawalRuleError(orderID, user, rule, limitError.Limit, limitError.Spent+amount, err)
+}
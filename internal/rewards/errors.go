@@ -0,0 +1,33 @@
+package rewards
+
+import (
+	"errors"
+	"strconv"
+)
+
+type RewardError struct {
+	ItemID   int64
+	NotFound bool
+	Invalid  bool
+	Err      error
+}
+
+func (e RewardError) Error() string {
+	return e.Err.Error()
+}
+
+func NewRewardNotFoundError(itemID int64) error {
+	return &RewardError{
+		ItemID:   itemID,
+		NotFound: true,
+		Err:      errors.New("вознаграждение " + strconv.FormatInt(itemID, 10) + " не найдено"),
+	}
+}
+
+func NewRewardValidationError(itemID int64, err error) error {
+	return &RewardError{
+		ItemID:  itemID,
+		Invalid: true,
+		Err:     err,
+	}
+}
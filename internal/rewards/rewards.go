@@ -0,0 +1,174 @@
+package rewards
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const maxRedemptionQuantity = 100
+
+type RewardManager interface {
+	GetItems(availableOnly bool) ([]database.RewardItem, error)
+	GetItem(itemID int64) (*database.RewardItem, error)
+	CreateItem(item *database.RewardItem) error
+	UpdateItem(item *database.RewardItem) error
+	DeleteItem(itemID int64) error
+	Redeem(user string, itemID int64, quantity int32) (*database.Redemption, error)
+	GetRedemptions(user string) ([]database.Redemption, error)
+	ChangeRedemptionStatus(redemptionID int64, status string) (*database.Redemption, error)
+}
+
+type rewardController struct {
+	model database.Storager
+}
+
+func NewRewards(m database.Storager) (RewardManager, error) {
+	if m == nil {
+		return nil, errors.New("не задано хранилище каталога вознаграждений")
+	}
+
+	return &rewardController{model: m}, nil
+}
+
+func (c *rewardController) GetItems(availableOnly bool) ([]database.RewardItem, error) {
+	return c.model.GetRewardItems(availableOnly)
+}
+
+func (c *rewardController) GetItem(itemID int64) (*database.RewardItem, error) {
+	item, err := c.model.GetRewardItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if item == nil {
+		return nil, NewRewardNotFoundError(itemID)
+	}
+
+	return item, nil
+}
+
+func (c *rewardController) CreateItem(item *database.RewardItem) error {
+	err := validateItem(item)
+	if err != nil {
+		return err
+	}
+
+	item.CreatedAt = database.CustomDateTime{Time: time.Now()}
+
+	err = c.model.CreateRewardItem(item)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("В каталог добавлено вознаграждение '%v' (%v)\n", item.ID, item.Name)
+	return nil
+}
+
+func (c *rewardController) UpdateItem(item *database.RewardItem) error {
+	err := validateItem(item)
+	if err != nil {
+		return err
+	}
+
+	err = c.model.UpdateRewardItem(item)
+	if err != nil {
+		return c.rewardError(item.ID, err)
+	}
+
+	updated, err := c.model.GetRewardItem(item.ID)
+	if err != nil {
+		return err
+	}
+
+	if updated != nil {
+		*item = *updated
+	}
+
+	log.Printf("Изменено вознаграждение '%v' (%v)\n", item.ID, item.Name)
+	return nil
+}
+
+func (c *rewardController) DeleteItem(itemID int64) error {
+	err := c.model.DeleteRewardItem(itemID)
+	if err != nil {
+		return c.rewardError(itemID, err)
+	}
+
+	log.Printf("Из каталога удалено вознаграждение '%v'\n", itemID)
+	return nil
+}
+
+func (c *rewardController) rewardError(itemID int64, err error) error {
+	var dbRewardError *database.DBRewardError
+	if errors.As(err, &dbRewardError) && dbRewardError.NotFound {
+		return NewRewardNotFoundError(itemID)
+	}
+
+	return err
+}
+
+func (c *rewardController) Redeem(user string, itemID int64, quantity int32) (*database.Redemption, error) {
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	if quantity < 0 || quantity > maxRedemptionQuantity {
+		return nil, NewRewardValidationError(itemID, errors.New("неверно указано количество вознаграждений"))
+	}
+
+	redemption := database.Redemption{
+		UserLogin: user,
+		ItemID:    itemID,
+		Quantity:  quantity,
+	}
+
+	err := c.model.Redeem(&redemption)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Пользователь '%v' обменял '%v' баллов на вознаграждение '%v'\n", user, redemption.Amount, redemption.ItemName)
+	return &redemption, nil
+}
+
+func (c *rewardController) GetRedemptions(user string) ([]database.Redemption, error) {
+	return c.model.GetRedemptions(user)
+}
+
+func (c *rewardController) ChangeRedemptionStatus(redemptionID int64, status string) (*database.Redemption, error) {
+	if status != database.RedemptionStatusFulfilled && status != database.RedemptionStatusCancelled {
+		return nil, NewRewardValidationError(0, errors.New("статус обмена баллов может быть изменён только на "+
+			database.RedemptionStatusFulfilled+" или "+database.RedemptionStatusCancelled))
+	}
+
+	redemption, err := c.model.UpdateRedemptionStatus(redemptionID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Статус обмена баллов '%v' изменён на '%v'\n", redemptionID, status)
+	return redemption, nil
+}
+
+func validateItem(item *database.RewardItem) error {
+	if item.Name == "" {
+		return NewRewardValidationError(item.ID, errors.New("не указано название вознаграждения"))
+	}
+
+	if item.Price <= 0 {
+		return NewRewardValidationError(item.ID, errors.New("стоимость вознаграждения должна быть больше нуля"))
+	}
+
+	if item.Stock != nil && *item.Stock < 0 {
+		return NewRewardValidationError(item.ID, errors.New("остаток вознаграждения не может быть отрицательным"))
+	}
+
+	if item.ValidFrom != nil && item.ValidUntil != nil && !item.ValidUntil.After(item.ValidFrom.Time) {
+		return NewRewardValidationError(item.ID, errors.New("неверно указан срок действия вознаграждения"))
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package vouchers
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+const (
+	codeAlphabet    = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	codeRandomChars = 11
+	codeGroupSize   = 4
+)
+
+var codeReplacer = strings.NewReplacer("-", "", " ", "", "O", "0", "I", "1", "L", "1")
+
+func checkCharacter(payload string) (byte, bool) {
+	base := len(codeAlphabet)
+	factor := 2
+	sum := 0
+
+	for i := len(payload) - 1; i >= 0; i-- {
+		codePoint := strings.IndexByte(codeAlphabet, payload[i])
+		if codePoint < 0 {
+			return 0, false
+		}
+
+		addend := factor * codePoint
+		addend = addend/base + addend%base
+		sum += addend
+
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+
+	return codeAlphabet[(base-sum%base)%base], true
+}
+
+func newCode() (string, error) {
+	payload := make([]byte, codeRandomChars)
+	max := big.NewInt(int64(len(codeAlphabet)))
+
+	for i := range payload {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		payload[i] = codeAlphabet[n.Int64()]
+	}
+
+	check, _ := checkCharacter(string(payload))
+
+	return string(payload) + string(check), nil
+}
+
+func normalizeCode(code string) string {
+	return codeReplacer.Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+func validCode(code string) bool {
+	if len(code) != codeRandomChars+1 {
+		return false
+	}
+
+	check, ok := checkCharacter(code[:codeRandomChars])
+
+	return ok && check == code[codeRandomChars]
+}
+
+func FormatCode(code string) string {
+	groups := make([]string, 0, len(code)/codeGroupSize+1)
+
+	for len(code) > codeGroupSize {
+		groups = append(groups, code[:codeGroupSize])
+		code = code[codeGroupSize:]
+	}
+
+	return strings.Join(append(groups, code), "-")
+}
@@ -0,0 +1,19 @@
+package vouchers
+
+type VoucherError struct {
+	Invalid     bool
+	RateLimited bool
+	Err         error
+}
+
+func (e VoucherError) Error() string {
+	return e.Err.Error()
+}
+
+func NewVoucherError(invalid, rateLimited bool, err error) error {
+	return &VoucherError{
+		Invalid:     invalid,
+		RateLimited: rateLimited,
+		Err:         err,
+	}
+}
@@ -0,0 +1,48 @@
+package vouchers
+
+import (
+	"sync"
+	"time"
+)
+
+type attemptLimiter struct {
+	lock     sync.Mutex
+	window   time.Duration
+	limit    int
+	attempts map[string][]time.Time
+}
+
+func newAttemptLimiter(limit int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		window:   window,
+		limit:    limit,
+		attempts: make(map[string][]time.Time),
+	}
+}
+
+func (l *attemptLimiter) allow(key string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	recent := l.attempts[key][:0]
+	for _, t := range l.attempts[key] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.attempts[key] = recent
+		return false
+	}
+
+	l.attempts[key] = append(recent, now)
+
+	for k, times := range l.attempts {
+		if len(times) > 0 && now.Sub(times[len(times)-1]) >= l.window {
+			delete(l.attempts, k)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,161 @@
+package vouchers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	maxBatchSize         = 10000
+	batchAttempts        = 3
+	attemptsPerMinute    = 5
+	failedAttemptsWindow = 24 * time.Hour
+	maxFailedAttempts    = 20
+	maxFailedIPAttempts  = 100
+)
+
+type VoucherManager interface {
+	GenerateBatch(batch *database.VoucherBatch) error
+	GetBatches() ([]database.VoucherBatch, error)
+	Redeem(user, code, ip string) (*database.Voucher, error)
+}
+
+type voucherController struct {
+	model   database.Storager
+	limiter *attemptLimiter
+}
+
+func NewVouchers(m database.Storager) (VoucherManager, error) {
+	if m == nil {
+		return nil, errors.New("не задано хранилище ваучеров")
+	}
+
+	return &voucherController{
+		model:   m,
+		limiter: newAttemptLimiter(attemptsPerMinute, time.Minute),
+	}, nil
+}
+
+func (c *voucherController) GenerateBatch(batch *database.VoucherBatch) error {
+	if batch.Quantity <= 0 || batch.Quantity > maxBatchSize {
+		return NewVoucherError(true, false, errors.New("количество ваучеров в партии должно быть от 1 до "+strconv.Itoa(maxBatchSize)))
+	}
+
+	if batch.Value <= 0 {
+		return NewVoucherError(true, false, errors.New("номинал ваучера должен быть больше нуля"))
+	}
+
+	if batch.ExpiresAt != nil && !batch.ExpiresAt.After(time.Now()) {
+		return NewVoucherError(true, false, errors.New("срок действия ваучеров уже истёк"))
+	}
+
+	batch.CreatedAt = database.CustomDateTime{Time: time.Now()}
+
+	var voucherError *database.DBVoucherError
+	var err error
+
+	for i := 0; i < batchAttempts; i++ {
+		var codes []string
+		codes, err = newCodes(int(batch.Quantity))
+		if err != nil {
+			return err
+		}
+
+		err = c.model.CreateVoucherBatch(batch, codes)
+		if err == nil {
+			batch.Codes = make([]string, 0, len(codes))
+			for _, code := range codes {
+				batch.Codes = append(batch.Codes, FormatCode(code))
+			}
+
+			log.Printf("Создана партия ваучеров '%v' из %v шт. номиналом '%v'\n", batch.ID, batch.Quantity, batch.Value)
+			return nil
+		}
+
+		if !errors.As(err, &voucherError) || !voucherError.Duplicate {
+			return err
+		}
+	}
+
+	return err
+}
+
+func newCodes(count int) ([]string, error) {
+	unique := make(map[string]struct{}, count)
+	codes := make([]string, 0, count)
+
+	for len(codes) < count {
+		code, err := newCode()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := unique[code]; ok {
+			continue
+		}
+
+		unique[code] = struct{}{}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+func (c *voucherController) GetBatches() ([]database.VoucherBatch, error) {
+	return c.model.GetVoucherBatches()
+}
+
+func (c *voucherController) Redeem(user, code, ip string) (*database.Voucher, error) {
+	now := time.Now()
+
+	if !c.limiter.allow("user:"+user, now) || (ip != "" && !c.limiter.allow("ip:"+ip, now)) {
+		return nil, NewVoucherError(false, true, errors.New("слишком много попыток активации ваучеров, повторите позже"))
+	}
+
+	failedByUser, failedByIP, err := c.model.CountFailedVoucherAttempts(user, ip, now.Add(-failedAttemptsWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	if failedByUser >= maxFailedAttempts {
+		log.Printf("Активация ваучеров заблокирована для пользователя '%v': %v неудачных попыток\n", user, failedByUser)
+		return nil, NewVoucherError(false, true, errors.New("превышено количество неудачных попыток активации ваучеров, повторите позже"))
+	}
+
+	if ip != "" && failedByIP >= maxFailedIPAttempts {
+		log.Printf("Активация ваучеров заблокирована для IP '%v': %v неудачных попыток\n", ip, failedByIP)
+		return nil, NewVoucherError(false, true, errors.New("превышено количество неудачных попыток активации ваучеров, повторите позже"))
+	}
+
+	code = normalizeCode(code)
+	if !validCode(code) {
+		c.recordAttempt(user, ip, false)
+		return nil, NewVoucherError(true, false, errors.New("ваучер не найден"))
+	}
+
+	voucher, err := c.model.RedeemVoucher(user, code)
+	if err != nil {
+		var voucherError *database.DBVoucherError
+		if errors.As(err, &voucherError) {
+			c.recordAttempt(user, ip, false)
+		}
+
+		return nil, err
+	}
+
+	c.recordAttempt(user, ip, true)
+
+	log.Printf("Пользователь '%v' активировал ваучер из партии '%v' на сумму '%v'\n", user, voucher.BatchID, voucher.Value)
+	return voucher, nil
+}
+
+func (c *voucherController) recordAttempt(user, ip string, success bool) {
+	err := c.model.AddVoucherAttempt(user, ip, success)
+	if err != nil {
+		log.Println("Ошибка при сохранении попытки активации ваучера:", err)
+	}
+}
@@ -0,0 +1,274 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+)
+
+const (
+	delayForDeliveringWebhooks = 5
+	deliveryTimeout            = 10 * time.Second
+	deliveryBatchSize          = 50
+	deliveryWorkers            = 8
+	maxDeliveryAttempts        = 8
+	baseRetryDelay             = 30 * time.Second
+	maxRetryDelay              = 6 * time.Hour
+	disableAfterFailures       = 20
+	maxErrorLength             = 500
+	maxResponseBodySize        = 64 * 1024
+
+	signatureHeader = "X-Gophermart-Signature"
+	eventHeader     = "X-Gophermart-Event"
+	deliveryHeader  = "X-Gophermart-Delivery"
+)
+
+type deliveryBody struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt string          `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func (c *webhookController) HandleEvent(event *database.Event) {
+	count, err := c.model.AddWebhookDeliveries(event)
+	if err != nil {
+		log.Println("Ошибка при постановке события в очередь доставки вебхуков:", err)
+		return
+	}
+
+	if count == 0 {
+		return
+	}
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *webhookController) deliverPending() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.wake:
+		case <-time.After(time.Second * delayForDeliveringWebhooks):
+		}
+
+		deliveries, err := c.model.GetDueWebhookDeliveries(time.Now(), deliveryBatchSize)
+		if err != nil {
+			log.Println("Ошибка при получении очереди доставки вебхуков:", err)
+			continue
+		}
+
+		c.deliverBatch(deliveries)
+	}
+}
+
+func (c *webhookController) deliverBatch(deliveries []database.WebhookDelivery) {
+	queue := make(chan *database.WebhookDelivery)
+	var wg sync.WaitGroup
+
+	for i := 0; i < deliveryWorkers && i < len(deliveries); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for delivery := range queue {
+				c.deliver(delivery)
+			}
+		}()
+	}
+
+	defer func() {
+		close(queue)
+		wg.Wait()
+	}()
+
+	for i := range deliveries {
+		select {
+		case <-c.done:
+			return
+		case queue <- &deliveries[i]:
+		}
+	}
+}
+
+func newDeliveryClient() http.Client {
+	dialer := &net.Dialer{
+		Timeout: deliveryTimeout,
+		Control: checkDialAddress,
+	}
+
+	return http.Client{
+		Timeout: deliveryTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: deliveryTimeout,
+			MaxIdleConnsPerHost: deliveryWorkers,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || isForbiddenIP(ip) {
+		return errors.New("доставка вебхуков на адрес " + host + " запрещена")
+	}
+
+	return nil
+}
+
+var (
+	sharedAddressSpace = mustParseCIDR("100.64.0.0/10")
+	nat64WellKnown     = mustParseCIDR("64:ff9b::/96")
+	nat64LocalUse      = mustParseCIDR("64:ff9b:1::/48")
+)
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+func isForbiddenIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	if nat64WellKnown.Contains(ip) {
+		return isForbiddenIP(ip[net.IPv6len-net.IPv4len:])
+	}
+
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip) || nat64LocalUse.Contains(ip)
+}
+
+func (c *webhookController) deliver(delivery *database.WebhookDelivery) {
+	body, err := json.Marshal(deliveryBody{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt.Time.Format(time.RFC3339),
+		Data:      json.RawMessage(delivery.Payload),
+	})
+	if err != nil {
+		log.Println("Ошибка при формировании тела вебхука:", err)
+		return
+	}
+
+	delivery.Attempts++
+	delivery.ResponseCode, err = c.send(delivery, body)
+
+	success := err == nil
+	if success {
+		now := time.Now()
+		delivery.Status = database.WebhookDeliveryDelivered
+		delivery.Error = ""
+		delivery.DeliveredAt = &database.CustomDateTime{Time: now}
+		log.Printf("Событие '%v' доставлено на вебхук '%v'\n", delivery.EventID, delivery.WebhookID)
+	} else {
+		delivery.Error = err.Error()
+		if len(delivery.Error) > maxErrorLength {
+			delivery.Error = delivery.Error[:maxErrorLength]
+		}
+
+		if delivery.Attempts >= maxDeliveryAttempts {
+			delivery.Status = database.WebhookDeliveryFailed
+		} else {
+			delivery.NextAttemptAt = database.CustomDateTime{Time: time.Now().Add(retryDelay(delivery.Attempts))}
+		}
+
+		log.Printf("Ошибка при доставке события '%v' на вебхук '%v', попытка %v: %v\n", delivery.EventID, delivery.WebhookID, delivery.Attempts, err)
+	}
+
+	err = c.model.UpdateWebhookDelivery(delivery)
+	if err != nil {
+		return
+	}
+
+	active, err := c.model.RecordWebhookResult(delivery.WebhookID, success, disableAfterFailures)
+	if err != nil {
+		return
+	}
+
+	if !success && !active {
+		log.Printf("Вебхук '%v' отключён после %v неудачных попыток доставки подряд\n", delivery.WebhookID, disableAfterFailures)
+	}
+}
+
+func (c *webhookController) send(delivery *database.WebhookDelivery, body []byte) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "gophermart-webhooks")
+	request.Header.Set(eventHeader, delivery.EventType)
+	request.Header.Set(deliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(signatureHeader, "t="+timestamp+",v1="+sign(delivery.Secret, timestamp, body))
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxResponseBodySize))
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return response.StatusCode, errors.New("получатель вебхука вернул статус " + strconv.Itoa(response.StatusCode))
+	}
+
+	return response.StatusCode, nil
+}
+
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func retryDelay(attempt int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckDialAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "93.184.216.34:443"},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{address: "127.0.0.1:80", wantErr: true},
+		{address: "[::1]:80", wantErr: true},
+		{address: "10.0.0.5:8080", wantErr: true},
+		{address: "172.16.3.4:80", wantErr: true},
+		{address: "192.168.1.1:80", wantErr: true},
+		{address: "169.254.169.254:80", wantErr: true},
+		{address: "[fe80::1]:80", wantErr: true},
+		{address: "[fd00::1]:80", wantErr: true},
+		{address: "0.0.0.0:80", wantErr: true},
+		{address: "[::]:80", wantErr: true},
+		{address: "[::ffff:127.0.0.1]:80", wantErr: true},
+		{address: "[::ffff:10.0.0.5]:80", wantErr: true},
+		{address: "[::ffff:169.254.169.254]:80", wantErr: true},
+		{address: "[::ffff:93.184.216.34]:443"},
+		{address: "100.64.0.1:80", wantErr: true},
+		{address: "100.127.255.254:80", wantErr: true},
+		{address: "100.128.0.1:80"},
+		{address: "[::ffff:100.64.0.1]:80", wantErr: true},
+		{address: "[64:ff9b::7f00:1]:80", wantErr: true},
+		{address: "[64:ff9b::a9fe:a9fe]:80", wantErr: true},
+		{address: "[64:ff9b::6440:1]:80", wantErr: true},
+		{address: "[64:ff9b:1:5db8:d8:2200::]:443", wantErr: true},
+		{address: "[64:ff9b::5db8:d822]:443"},
+		{address: "224.0.0.1:80", wantErr: true},
+		{address: "example.com:80", wantErr: true},
+		{address: "127.0.0.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := checkDialAddress("tcp", tt.address, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkDialAddress(%q) = %v, ожидалась ошибка: %v", tt.address, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		want    time.Duration
+	}{
+		{name: "нулевая попытка", attempt: 0, want: baseRetryDelay},
+		{name: "первая попытка", attempt: 1, want: baseRetryDelay},
+		{name: "вторая попытка", attempt: 2, want: 2 * baseRetryDelay},
+		{name: "третья попытка", attempt: 3, want: 4 * baseRetryDelay},
+		{name: "ограничение сверху", attempt: 20, want: maxRetryDelay},
+		{name: "большой номер попытки", attempt: 1000, want: maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryDelay(tt.attempt); got != tt.want {
+				t.Errorf("retryDelay(%v) = %v, ожидалось %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package webhooks
+
+import (
+	"errors"
+	"strconv"
+)
+
+type WebhookError struct {
+	WebhookID int64
+	NotFound  bool
+	Invalid   bool
+	Err       error
+}
+
+func (e WebhookError) Error() string {
+	return e.Err.Error()
+}
+
+func NewWebhookNotFoundError(webhookID int64) error {
+	return &WebhookError{
+		WebhookID: webhookID,
+		NotFound:  true,
+		Err:       errors.New("вебхук " + strconv.FormatInt(webhookID, 10) + " не найден"),
+	}
+}
+
+func NewWebhookValidationError(webhookID int64, err error) error {
+	return &WebhookError{
+		WebhookID: webhookID,
+		Invalid:   true,
+		Err:       err,
+	}
+}
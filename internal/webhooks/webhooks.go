@@ -0,0 +1,231 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/database"
+	"github.com/StainlessSteelSnake/gophermart-loyalty/internal/events"
+)
+
+const (
+	maxWebhooksPerUser = 10
+	minSecretLength    = 16
+	maxSecretLength    = 128
+	maxURLLength       = 2048
+	secretBytes        = 32
+	deliveryLogSize    = 100
+)
+
+type WebhookManager interface {
+	GetWebhooks(user string) ([]database.Webhook, error)
+	GetWebhook(user string, webhookID int64) (*database.Webhook, error)
+	CreateWebhook(webhook *database.Webhook) error
+	UpdateWebhook(webhook *database.Webhook) error
+	DeleteWebhook(user string, webhookID int64) error
+	GetDeliveries(user string, webhookID int64) ([]database.WebhookDelivery, error)
+	Close()
+}
+
+type webhookController struct {
+	model  database.Storager
+	client http.Client
+	wake   chan struct{}
+	done   chan struct{}
+}
+
+func NewWebhooks(m database.Storager, bus events.Bus) (WebhookManager, error) {
+	if m == nil {
+		return nil, errors.New("не задано хранилище вебхуков")
+	}
+
+	result := &webhookController{
+		model:  m,
+		client: newDeliveryClient(),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	if bus != nil {
+		bus.AddListener(result)
+	}
+
+	go result.deliverPending()
+
+	return result, nil
+}
+
+func (c *webhookController) GetWebhooks(user string) ([]database.Webhook, error) {
+	return c.model.GetWebhooks(user)
+}
+
+func (c *webhookController) GetWebhook(user string, webhookID int64) (*database.Webhook, error) {
+	webhook, err := c.model.GetWebhook(user, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook == nil {
+		return nil, NewWebhookNotFoundError(webhookID)
+	}
+
+	return webhook, nil
+}
+
+func (c *webhookController) CreateWebhook(webhook *database.Webhook) error {
+	err := validateWebhook(webhook)
+	if err != nil {
+		return err
+	}
+
+	if webhook.Secret == "" {
+		webhook.Secret, err = newSecret()
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(webhook.Secret) < minSecretLength || len(webhook.Secret) > maxSecretLength {
+		return NewWebhookValidationError(0, errors.New("длина секрета вебхука должна быть от "+strconv.Itoa(minSecretLength)+" до "+strconv.Itoa(maxSecretLength)+" символов"))
+	}
+
+	webhooks, err := c.model.GetWebhooks(webhook.UserLogin)
+	if err != nil {
+		return err
+	}
+
+	if len(webhooks) >= maxWebhooksPerUser {
+		return NewWebhookValidationError(0, errors.New("превышено допустимое количество вебхуков пользователя: "+strconv.Itoa(maxWebhooksPerUser)))
+	}
+
+	webhook.Active = true
+	webhook.CreatedAt = database.CustomDateTime{Time: time.Now()}
+
+	err = c.model.CreateWebhook(webhook)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Пользователь '%v' зарегистрировал вебхук '%v' (%v)\n", webhook.UserLogin, webhook.ID, webhook.URL)
+	return nil
+}
+
+func (c *webhookController) UpdateWebhook(webhook *database.Webhook) error {
+	err := validateWebhook(webhook)
+	if err != nil {
+		return err
+	}
+
+	err = c.model.UpdateWebhook(webhook)
+	if err != nil {
+		return c.webhookError(webhook.ID, err)
+	}
+
+	updated, err := c.GetWebhook(webhook.UserLogin, webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	*webhook = *updated
+
+	log.Printf("Пользователь '%v' изменил вебхук '%v'\n", webhook.UserLogin, webhook.ID)
+	return nil
+}
+
+func (c *webhookController) DeleteWebhook(user string, webhookID int64) error {
+	err := c.model.DeleteWebhook(user, webhookID)
+	if err != nil {
+		return c.webhookError(webhookID, err)
+	}
+
+	log.Printf("Пользователь '%v' удалил вебхук '%v'\n", user, webhookID)
+	return nil
+}
+
+func (c *webhookController) GetDeliveries(user string, webhookID int64) ([]database.WebhookDelivery, error) {
+	_, err := c.GetWebhook(user, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.model.GetWebhookDeliveries(webhookID, deliveryLogSize)
+}
+
+func (c *webhookController) Close() {
+	close(c.done)
+}
+
+func (c *webhookController) webhookError(webhookID int64, err error) error {
+	var dbWebhookError *database.DBWebhookError
+	if errors.As(err, &dbWebhookError) && dbWebhookError.NotFound {
+		return NewWebhookNotFoundError(webhookID)
+	}
+
+	return err
+}
+
+func validateWebhook(webhook *database.Webhook) error {
+	if len(webhook.URL) == 0 || len(webhook.URL) > maxURLLength {
+		return NewWebhookValidationError(webhook.ID, errors.New("не указан адрес вебхука или он слишком длинный"))
+	}
+
+	address, err := url.Parse(webhook.URL)
+	if err != nil || (address.Scheme != "http" && address.Scheme != "https") || address.Host == "" {
+		return NewWebhookValidationError(webhook.ID, errors.New("адрес вебхука должен быть абсолютным URL со схемой http или https"))
+	}
+
+	if ip := net.ParseIP(address.Hostname()); ip != nil && isForbiddenIP(ip) {
+		return NewWebhookValidationError(webhook.ID, errors.New("адрес вебхука не может указывать на локальную или внутреннюю сеть"))
+	}
+
+	if len(webhook.EventTypes) == 0 {
+		return NewWebhookValidationError(webhook.ID, errors.New("не указаны типы событий вебхука"))
+	}
+
+	eventTypes := make([]string, 0, len(webhook.EventTypes))
+	seen := make(map[string]struct{}, len(webhook.EventTypes))
+	for _, eventType := range webhook.EventTypes {
+		if !isEventType(eventType) {
+			return NewWebhookValidationError(webhook.ID, errors.New("неизвестный тип события "+eventType))
+		}
+
+		if _, ok := seen[eventType]; ok {
+			continue
+		}
+
+		seen[eventType] = struct{}{}
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	webhook.EventTypes = eventTypes
+
+	return nil
+}
+
+func isEventType(eventType string) bool {
+	for _, t := range events.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, secretBytes)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}